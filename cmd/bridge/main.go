@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/postgres"
+	"github.com/soggycactus/sharechat.dev/sharechat/redis"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
+)
+
+// roomIDs collects the rooms passed with every --room flag
+type roomIDs []string
+
+func (r *roomIDs) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *roomIDs) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+var (
+	rooms     roomIDs
+	nameA     string
+	nameB     string
+	queueA    string
+	queueB    string
+	codecName string
+	nodeID    string
+)
+
+// openCluster connects to the Postgres database & Queue of a cluster, configured by the
+// environment variables starting with prefix
+func openCluster(prefix, name, queueType string, encoder *wire.Encoder) sharechat.Cluster {
+	dbstring := os.Getenv(prefix + "_POSTGRES_URL")
+	db, err := sql.Open("postgres", dbstring)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pgdb := postgres.NewDB(db)
+
+	var queue sharechat.Queue
+	switch queueType {
+	case "pubsub", "streams":
+		client := redisv8.NewClient(&redisv8.Options{
+			Addr:     os.Getenv(prefix + "_REDIS_HOST"),
+			Username: os.Getenv(prefix + "_REDIS_USER"),
+			Password: os.Getenv(prefix + "_REDIS_PASS"),
+		})
+		if queueType == "pubsub" {
+			queue = redis.NewQueue(*client).WithEncoder(encoder)
+		} else {
+			// the bridge only reads streams, so it leaves trimming to the cluster's own nodes
+			queue = redis.NewStreamQueue(*client, 0).WithEncoder(encoder)
+		}
+	case "postgres":
+		queue = postgres.NewQueue(pgdb, dbstring).WithOrigin(nodeID)
+	default:
+		log.Fatalf("unknown queue %q for cluster %s", queueType, name)
+	}
+
+	return sharechat.Cluster{
+		Name: name,
+		Controller: sharechat.NewController(sharechat.NewControllerInput{
+			RoomRepo:    postgres.NewRoomRepository(pgdb),
+			MessageRepo: postgres.NewMessageRepository(pgdb),
+			MemberRepo:  postgres.NewMemberRepository(pgdb),
+			Queue:       queue,
+			OutboxRepo:  postgres.NewOutboxRepository(pgdb),
+		}),
+	}
+}
+
+func main() {
+	flag.Var(&rooms, "room", "the ID of a room to bridge; may be repeated")
+	flag.StringVar(&nameA, "a-name", "a", "the name of the first cluster, configured by the A_POSTGRES_URL, A_REDIS_HOST, A_REDIS_USER & A_REDIS_PASS variables")
+	flag.StringVar(&nameB, "b-name", "b", "the name of the second cluster, configured by the B_POSTGRES_URL, B_REDIS_HOST, B_REDIS_USER & B_REDIS_PASS variables")
+	flag.StringVar(&queueA, "a-queue", "pubsub", "the queue of the first cluster: 'pubsub', 'streams' or 'postgres'")
+	flag.StringVar(&queueB, "b-queue", "pubsub", "the queue of the second cluster: 'pubsub', 'streams' or 'postgres'")
+	flag.StringVar(&codecName, "codec", "json", "how relayed messages are encoded on the Redis queues: 'json' or 'msgpack'")
+	flag.StringVar(&nodeID, "node-id", "", "the name of this node in published messages; defaults to the hostname")
+	flag.Parse()
+
+	if nameA == nameB {
+		log.Fatal("the clusters must have different names")
+	}
+	if len(rooms) == 0 {
+		log.Fatal("at least one --room is required")
+	}
+
+	codec, err := wire.ParseCodec(codecName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if nodeID == "" {
+		if nodeID, err = os.Hostname(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	encoder := wire.NewEncoder(codec, nodeID)
+
+	bridge := sharechat.NewBridge(
+		openCluster("A", nameA, queueA, encoder),
+		openCluster("B", nameB, queueB, encoder),
+	)
+
+	for _, roomID := range rooms {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := bridge.BridgeRoom(ctx, roomID)
+		cancel()
+		if err != nil {
+			log.Fatalf("failed to bridge room %s: %v", roomID, err)
+		}
+		log.Printf("bridging room %s between %s & %s", roomID, nameA, nameB)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+
+	log.Print("stopping bridge")
+	bridge.Close()
+}
@@ -4,39 +4,104 @@ import (
 	"context"
 	"flag"
 	"log"
+	"net"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/rs/cors"
 	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/grpc"
 	"github.com/soggycactus/sharechat.dev/sharechat/http"
+	"github.com/soggycactus/sharechat.dev/sharechat/irc"
 	"github.com/soggycactus/sharechat.dev/sharechat/memory"
 )
 
 var (
 	upgrader       websocket.Upgrader = websocket.Upgrader{HandshakeTimeout: 5 * time.Second}
 	allowedOrigins http.AllowedOrigins
+	roomTTL        time.Duration
+	reapInterval   time.Duration
+	outboxInterval time.Duration
+
+	retentionMaxAge      time.Duration
+	retentionMaxMessages int
+	compactionInterval   time.Duration
+	pollSessionTTL       time.Duration
+	grpcAddr             string
+	ircAddr              string
+	adminAddr            string
 )
 
 func main() {
 	flag.Var(&allowedOrigins, "allowed-origin", "allowed origins for CORS")
+	flag.DurationVar(&roomTTL, "room-ttl", 0, "delete rooms after this long without any messages; 0 disables expiry")
+	flag.DurationVar(&reapInterval, "reap-interval", time.Minute, "how often to check for inactive rooms")
+	flag.DurationVar(&outboxInterval, "outbox-interval", 5*time.Second, "how often to retry publishing messages left in the outbox")
+	flag.DurationVar(&retentionMaxAge, "retention-max-age", 0, "purge messages older than this unless their room overrides it; 0 keeps messages forever")
+	flag.IntVar(&retentionMaxMessages, "retention-max-messages", 0, "keep at most this many messages per room unless the room overrides it; 0 keeps every message")
+	flag.DurationVar(&compactionInterval, "compaction-interval", 10*time.Minute, "how often to purge messages exceeding their retention policy")
+	flag.DurationVar(&pollSessionTTL, "poll-session-ttl", http.DefaultPollSessionTTL, "how long a long polling session lives without being polled before its member leaves the room")
+	flag.StringVar(&grpcAddr, "grpc-addr", "", "the address to serve the gRPC API on, e.g. ':9090'; the gRPC API is disabled if empty")
+	flag.StringVar(&ircAddr, "irc-addr", "", "the address to serve the IRC gateway on, e.g. ':6667'; the IRC gateway is disabled if empty")
+	flag.StringVar(&adminAddr, "admin-addr", "", "the internal address to serve room deletion, room retention overrides & the metrics at /debug/vars on, e.g. '127.0.0.1:9100'; none are served if empty")
 	flag.Parse()
 
-	roomRepo := memory.NewRoomRepo()
 	messageRepo := memory.NewMessageRepo()
 	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
 	controller := sharechat.NewController(sharechat.NewControllerInput{
 		RoomRepo:    roomRepo,
 		MessageRepo: messageRepo,
 		MemberRepo:  memberRepo,
 		Queue:       memory.NewQueue(),
+		OutboxRepo:  messageRepo,
+		RoomTTL:     roomTTL,
+		Retention: sharechat.RetentionPolicy{
+			MaxAge:      retentionMaxAge,
+			MaxMessages: retentionMaxMessages,
+		},
 		Healthcheck: func(c context.Context) error { return nil },
 	})
 
+	go controller.ReapRooms(context.Background(), reapInterval)
+	go controller.RelayOutbox(context.Background(), outboxInterval)
+	go controller.CompactMessages(context.Background(), compactionInterval)
+
 	server := http.NewServer(controller, upgrader, cors.Options{
 		AllowedOrigins: allowedOrigins,
 		AllowedMethods: []string{"GET", "HEAD", "POST", "OPTIONS"},
-	})
+		AllowedHeaders: []string{"Accept", "Content-Type", "X-Requested-With", http.SessionHeader},
+	}).WithPollSessionTTL(pollSessionTTL)
+
+	if grpcAddr != "" {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("starting gRPC server on %s", grpcAddr)
+		go func() {
+			log.Fatal(grpc.NewServer(controller).Server.Serve(listener))
+		}()
+	}
+
+	if ircAddr != "" {
+		listener, err := net.Listen("tcp", ircAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("starting IRC gateway on %s", ircAddr)
+		go func() {
+			log.Fatal(irc.NewServer(controller).Serve(listener))
+		}()
+	}
+
+	if adminAddr != "" {
+		log.Printf("starting admin server on %s", adminAddr)
+		go func() {
+			server.Admin.Addr = adminAddr
+			log.Fatal(server.Admin.ListenAndServe())
+		}()
+	}
 
 	log.Print("starting memorychat server on port 8080")
 	log.Fatal(server.Server.ListenAndServe())
@@ -6,22 +6,44 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"time"
 
 	redisv8 "github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
+	natsgo "github.com/nats-io/nats.go"
 	"github.com/pressly/goose/v3"
 	"github.com/rs/cors"
 	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/grpc"
 	"github.com/soggycactus/sharechat.dev/sharechat/http"
+	"github.com/soggycactus/sharechat.dev/sharechat/irc"
+	"github.com/soggycactus/sharechat.dev/sharechat/nats"
 	"github.com/soggycactus/sharechat.dev/sharechat/postgres"
 	"github.com/soggycactus/sharechat.dev/sharechat/redis"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
 )
 
 var (
 	upgrader       websocket.Upgrader = websocket.Upgrader{HandshakeTimeout: 5 * time.Second}
 	allowedOrigins http.AllowedOrigins
+	roomTTL        time.Duration
+	reapInterval   time.Duration
+	outboxInterval time.Duration
+
+	retentionMaxAge      time.Duration
+	retentionMaxMessages int
+	compactionInterval   time.Duration
+	pollSessionTTL       time.Duration
+	grpcAddr             string
+	ircAddr              string
+	adminAddr            string
+
+	queueType       string
+	streamMaxLength int64
+	codecName       string
+	nodeID          string
 )
 
 func main() {
@@ -33,8 +55,26 @@ func main() {
 	redisUser := os.Getenv("REDIS_USER")
 	redisPass := os.Getenv("REDIS_PASS")
 	redisHost := os.Getenv("REDIS_HOST")
+	natsURL := os.Getenv("NATS_URL")
+	if natsURL == "" {
+		natsURL = natsgo.DefaultURL
+	}
 
 	flag.Var(&allowedOrigins, "allowed-origin", "allowed origins for CORS")
+	flag.DurationVar(&roomTTL, "room-ttl", 0, "delete rooms after this long without any messages; 0 disables expiry")
+	flag.DurationVar(&reapInterval, "reap-interval", time.Minute, "how often to check for inactive rooms")
+	flag.DurationVar(&outboxInterval, "outbox-interval", 5*time.Second, "how often to retry publishing messages left in the outbox")
+	flag.DurationVar(&retentionMaxAge, "retention-max-age", 0, "purge messages older than this unless their room overrides it; 0 keeps messages forever")
+	flag.IntVar(&retentionMaxMessages, "retention-max-messages", 0, "keep at most this many messages per room unless the room overrides it; 0 keeps every message")
+	flag.DurationVar(&compactionInterval, "compaction-interval", 10*time.Minute, "how often to purge messages exceeding their retention policy")
+	flag.DurationVar(&pollSessionTTL, "poll-session-ttl", http.DefaultPollSessionTTL, "how long a long polling session lives without being polled before its member leaves the room")
+	flag.StringVar(&grpcAddr, "grpc-addr", "", "the address to serve the gRPC API on, e.g. ':9090'; the gRPC API is disabled if empty")
+	flag.StringVar(&ircAddr, "irc-addr", "", "the address to serve the IRC gateway on, e.g. ':6667'; the IRC gateway is disabled if empty")
+	flag.StringVar(&adminAddr, "admin-addr", "", "the internal address to serve room deletion, room retention overrides & the metrics at /debug/vars on, e.g. '127.0.0.1:9100'; none are served if empty")
+	flag.StringVar(&queueType, "queue", "pubsub", "the queue to use: Redis 'pubsub', Redis 'streams' to replay messages missed while a subscription reconnects, 'postgres', or NATS JetStream with 'nats'")
+	flag.Int64Var(&streamMaxLength, "stream-max-length", 1000, "the approximate number of messages kept in each room's stream when --queue=streams")
+	flag.StringVar(&codecName, "codec", "json", "how messages are encoded on the Redis & NATS queues: 'json' or 'msgpack'; the Postgres queue always uses JSON")
+	flag.StringVar(&nodeID, "node-id", "", "the name of this node in published messages; defaults to the hostname")
 	flag.Parse()
 
 	log.Printf("allowed origins: %v", allowedOrigins)
@@ -52,26 +92,76 @@ func main() {
 		log.Fatal(err)
 	}
 
-	redisClient := redisv8.NewClient(&redisv8.Options{
-		Addr:     redisHost,
-		Username: redisUser,
-		Password: redisPass,
-	})
+	pgdb := postgres.NewDB(db)
+
+	codec, err := wire.ParseCodec(codecName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if nodeID == "" {
+		if nodeID, err = os.Hostname(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	encoder := wire.NewEncoder(codec, nodeID)
+
+	var queue sharechat.Queue
+	var pingQueue func(context.Context) error
+	switch queueType {
+	case "pubsub", "streams":
+		redisClient := redisv8.NewClient(&redisv8.Options{
+			Addr:     redisHost,
+			Username: redisUser,
+			Password: redisPass,
+		})
+
+		if queueType == "pubsub" {
+			queue = redis.NewQueue(*redisClient).WithEncoder(encoder)
+		} else {
+			queue = redis.NewStreamQueue(*redisClient, streamMaxLength).WithEncoder(encoder)
+		}
+		pingQueue = func(c context.Context) error { return redisClient.Ping(c).Err() }
+	case "postgres":
+		postgresQueue := postgres.NewQueue(pgdb, dbstring).WithOrigin(nodeID)
+		queue = postgresQueue
+		pingQueue = postgresQueue.Ping
+	case "nats":
+		conn, err := natsgo.Connect(natsURL, natsgo.MaxReconnects(-1))
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	roomRepo := postgres.NewRoomRepository(db, "postgres")
-	messageRepo := postgres.NewMessageRepository(db, "postgres")
-	memberRepo := postgres.NewMemberRepository(db, "postgres")
+		// consumers are named after the node, so it resumes them after restarting
+		natsQueue, err := nats.NewQueue(conn, nodeID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		queue = natsQueue.WithEncoder(encoder)
+		pingQueue = natsQueue.Ping
+	default:
+		log.Fatalf("unknown queue %q", queueType)
+	}
+
+	roomRepo := postgres.NewRoomRepository(pgdb)
+	messageRepo := postgres.NewMessageRepository(pgdb)
+	memberRepo := postgres.NewMemberRepository(pgdb)
 	controller := sharechat.NewController(sharechat.NewControllerInput{
 		RoomRepo:    roomRepo,
 		MessageRepo: messageRepo,
 		MemberRepo:  memberRepo,
-		Queue:       redis.NewQueue(*redisClient),
+		Queue:       queue,
+		OutboxRepo:  postgres.NewOutboxRepository(pgdb),
+		RoomTTL:     roomTTL,
+		Retention: sharechat.RetentionPolicy{
+			MaxAge:      retentionMaxAge,
+			MaxMessages: retentionMaxMessages,
+		},
 		Healthcheck: func(c context.Context) error {
 			if err := db.PingContext(c); err != nil {
 				return err
 			}
 
-			if err := redisClient.Ping(c).Err(); err != nil {
+			if err := pingQueue(c); err != nil {
 				return err
 			}
 
@@ -79,10 +169,45 @@ func main() {
 		},
 	})
 
+	go controller.ReapRooms(context.Background(), reapInterval)
+	go controller.RelayOutbox(context.Background(), outboxInterval)
+	go controller.CompactMessages(context.Background(), compactionInterval)
+
 	server := http.NewServer(controller, upgrader, cors.Options{
 		AllowedOrigins: allowedOrigins,
 		AllowedMethods: []string{"GET", "HEAD", "POST", "OPTIONS"},
-	})
+		AllowedHeaders: []string{"Accept", "Content-Type", "X-Requested-With", http.SessionHeader},
+	}).WithPollSessionTTL(pollSessionTTL)
+
+	if grpcAddr != "" {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("starting gRPC server on %s", grpcAddr)
+		go func() {
+			log.Fatal(grpc.NewServer(controller).Server.Serve(listener))
+		}()
+	}
+
+	if ircAddr != "" {
+		listener, err := net.Listen("tcp", ircAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("starting IRC gateway on %s", ircAddr)
+		go func() {
+			log.Fatal(irc.NewServer(controller).Serve(listener))
+		}()
+	}
+
+	if adminAddr != "" {
+		log.Printf("starting admin server on %s", adminAddr)
+		go func() {
+			server.Admin.Addr = adminAddr
+			log.Fatal(server.Admin.ListenAndServe())
+		}()
+	}
 
 	log.Print("starting sharechat server on port 8080")
 	log.Fatal(server.Server.ListenAndServe())
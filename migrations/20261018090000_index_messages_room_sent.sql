@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX messages_room_id_sent_idx ON messages (room_id, sent);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX messages_room_id_sent_idx;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reactions (
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    message_id VARCHAR NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
+    member_id VARCHAR NOT NULL REFERENCES members(member_id) ON DELETE CASCADE,
+    emoji VARCHAR NOT NULL,
+    PRIMARY KEY (message_id, member_id, emoji)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE reactions;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages ADD COLUMN parent_id VARCHAR REFERENCES messages(message_id) ON DELETE CASCADE;
+CREATE INDEX messages_parent_id_sent_idx ON messages (parent_id, sent);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX messages_parent_id_sent_idx;
+ALTER TABLE messages DROP COLUMN parent_id;
+-- +goose StatementEnd
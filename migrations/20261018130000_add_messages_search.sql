@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', message)) STORED;
+CREATE INDEX messages_search_idx ON messages USING GIN (search);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX messages_search_idx;
+ALTER TABLE messages DROP COLUMN search;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox (
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    message_id VARCHAR PRIMARY KEY REFERENCES messages(message_id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX outbox_next_attempt_at_idx ON outbox (next_attempt_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX outbox_next_attempt_at_idx;
+DROP TABLE outbox;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rooms ADD COLUMN last_seq BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN seq BIGINT;
+
+-- number existing messages in the order they were sent
+UPDATE messages m SET seq = numbered.seq
+FROM (
+    SELECT message_id, ROW_NUMBER() OVER (PARTITION BY room_id ORDER BY sent, message_id) AS seq
+    FROM messages
+) numbered
+WHERE m.message_id = numbered.message_id;
+
+UPDATE rooms r SET last_seq = COALESCE((SELECT MAX(seq) FROM messages m WHERE m.room_id = r.room_id), 0);
+
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+CREATE UNIQUE INDEX messages_room_id_seq_idx ON messages (room_id, seq);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX messages_room_id_seq_idx;
+ALTER TABLE messages DROP COLUMN seq;
+ALTER TABLE rooms DROP COLUMN last_seq;
+-- +goose StatementEnd
@@ -0,0 +1,250 @@
+package sharechat
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// relayTimeout bounds storing & publishing a single relayed message
+const relayTimeout = 5 * time.Second
+
+// Relay stores & publishes a message relayed from another cluster as if a member had sent it to this
+// Controller. Members joining & leaving are replicated, so they are listed with the room here too.
+// The message is stored with its Cluster, so it keeps it when the outbox relay publishes it & the
+// Bridge does not relay it back to the cluster it came from.
+func (c *Controller) Relay(ctx context.Context, message Message) error {
+	var result *Message
+	stored := true
+	var err error
+
+	switch message.Type {
+	case MemberJoined:
+		result, err = c.memberRepo.InsertMember(ctx, Member{ID: message.MemberID, Name: message.MemberName, RoomID: message.RoomID, Cluster: message.Cluster})
+	case MemberLeft:
+		result, err = c.memberRepo.DeleteMember(ctx, Member{ID: message.MemberID, Name: message.MemberName, RoomID: message.RoomID, Cluster: message.Cluster})
+	default:
+		result, stored, err = c.store(ctx, message)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Cluster = message.Cluster
+	return c.publishResult(ctx, *result, stored)
+}
+
+// Cluster is a deployment connected by a Bridge. Name tags the messages relayed from it, and must
+// differ from the name of the other cluster.
+type Cluster struct {
+	Name string
+	// Controller is built on the repositories & Queue of the cluster
+	Controller *Controller
+}
+
+// NewBridge creates a Bridge relaying the messages of bridged rooms between two clusters
+func NewBridge(a, b Cluster) *Bridge {
+	return &Bridge{
+		a:     a,
+		b:     b,
+		rooms: make(map[string]context.CancelFunc),
+	}
+}
+
+// Bridge shares rooms between two independent deployments. The messages of a bridged room published in
+// either cluster are stored & published in the other, tagged with the cluster they were sent in; messages
+// tagged with the cluster they would be relayed to came from it, and are not relayed back.
+// Messages published while the Bridge is not subscribed to a cluster are not relayed, and room expiry
+// is not relayed either: each cluster decides when to delete its rooms.
+type Bridge struct {
+	a, b Cluster
+
+	mu    sync.Mutex
+	rooms map[string]context.CancelFunc
+	wg    sync.WaitGroup
+}
+
+// BridgeRoom creates the room in the cluster missing it, replicates the members of each cluster to
+// the other, then relays the messages of the room until UnbridgeRoom or Close is called.
+// ErrRoomNotFound is returned if neither cluster has the room.
+func (b *Bridge) BridgeRoom(ctx context.Context, roomID string) error {
+	b.mu.Lock()
+	_, bridged := b.rooms[roomID]
+	b.mu.Unlock()
+	if bridged {
+		return nil
+	}
+
+	if err := b.replicateRoom(ctx, roomID); err != nil {
+		return err
+	}
+
+	// subscribe before replicating members, so members joining meanwhile are relayed
+	subscriptionA, err := b.a.Controller.queue.Subscribe(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	subscriptionB, err := b.b.Controller.queue.Subscribe(ctx, roomID)
+	if err != nil {
+		subscriptionA.Close()
+		return err
+	}
+
+	if err := b.replicateMembers(ctx, roomID, b.a, b.b); err == nil {
+		err = b.replicateMembers(ctx, roomID, b.b, b.a)
+	}
+	if err != nil {
+		subscriptionA.Close()
+		subscriptionB.Close()
+		return err
+	}
+
+	relayCtx, cancel := context.WithCancel(context.Background())
+	b.mu.Lock()
+	b.rooms[roomID] = cancel
+	b.mu.Unlock()
+
+	b.wg.Add(2)
+	go b.relay(relayCtx, roomID, b.a, b.b, subscriptionA)
+	go b.relay(relayCtx, roomID, b.b, b.a, subscriptionB)
+	return nil
+}
+
+// UnbridgeRoom stops relaying the messages of the room. Rooms & members already replicated are kept.
+func (b *Bridge) UnbridgeRoom(roomID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cancel, ok := b.rooms[roomID]; ok {
+		cancel()
+		delete(b.rooms, roomID)
+	}
+}
+
+// Close stops relaying every bridged room & waits for the messages being relayed
+func (b *Bridge) Close() {
+	b.mu.Lock()
+	for roomID, cancel := range b.rooms {
+		cancel()
+		delete(b.rooms, roomID)
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+// replicateRoom creates the room in the cluster that does not have it
+func (b *Bridge) replicateRoom(ctx context.Context, roomID string) error {
+	roomA, errA := b.a.Controller.roomRepo.GetRoom(ctx, roomID)
+	if errA != nil && !errors.Is(errA, ErrRoomNotFound) {
+		return errA
+	}
+	roomB, errB := b.b.Controller.roomRepo.GetRoom(ctx, roomID)
+	if errB != nil && !errors.Is(errB, ErrRoomNotFound) {
+		return errB
+	}
+
+	switch {
+	case roomA == nil && roomB == nil:
+		return ErrRoomNotFound
+	case roomA == nil:
+		return b.a.Controller.roomRepo.InsertRoom(ctx, roomB)
+	case roomB == nil:
+		return b.b.Controller.roomRepo.InsertRoom(ctx, roomA)
+	}
+	return nil
+}
+
+// replicateMembers relays a joined message for every member of the room in from that is missing in to
+func (b *Bridge) replicateMembers(ctx context.Context, roomID string, from, to Cluster) error {
+	members, err := from.Controller.memberRepo.GetMembersByRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	existing, err := to.Controller.memberRepo.GetMembersByRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	replicated := make(map[string]struct{}, len(*existing))
+	for _, member := range *existing {
+		replicated[member.ID] = struct{}{}
+	}
+
+	for _, member := range *members {
+		if _, ok := replicated[member.ID]; ok {
+			continue
+		}
+
+		joined := NewMemberJoinedMessage(member)
+		joined.Cluster = from.Name
+		if err := to.Controller.Relay(ctx, joined); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relay relays the messages of the room published in from to the other cluster until ctx is done,
+// subscribing again with backoff if the subscription fails
+func (b *Bridge) relay(ctx context.Context, roomID string, from, to Cluster, subscription Subscription) {
+	defer b.wg.Done()
+	defer func() {
+		if subscription != nil {
+			subscription.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-subscription.Messages():
+			if !ok {
+				log.Printf("bridge subscription to room %s in %s failed, subscribing again: %v", roomID, from.Name, subscription.Err())
+				subscription.Close()
+				if subscription = b.resubscribe(ctx, roomID, from); subscription == nil {
+					return
+				}
+				continue
+			}
+
+			switch {
+			case message.Cluster == to.Name:
+				// relayed from the other cluster in the first place
+				continue
+			case message.Type == SendFailed || message.Type == RoomExpired:
+				continue
+			case message.Cluster == "":
+				message.Cluster = from.Name
+			}
+
+			relayCtx, cancel := context.WithTimeout(ctx, relayTimeout)
+			if err := to.Controller.Relay(relayCtx, message); err != nil {
+				log.Printf("failed to relay %s message %s of room %s from %s to %s: %v", message.Type, message.ID, roomID, from.Name, to.Name, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// resubscribe subscribes to the room in the cluster again, retrying with backoff until it
+// succeeds or ctx is done, in which case it returns nil
+func (b *Bridge) resubscribe(ctx context.Context, roomID string, cluster Cluster) Subscription {
+	for attempts := 0; ; attempts++ {
+		subscribeCtx, cancel := context.WithTimeout(ctx, recoveryTimeout)
+		subscription, err := cluster.Controller.queue.Subscribe(subscribeCtx, roomID)
+		cancel()
+		if err == nil {
+			return subscription
+		}
+
+		backoff := recoveryBackoff(attempts)
+		log.Printf("failed to subscribe to room %s in %s (attempt %d), retrying in %s: %v", roomID, cluster.Name, attempts+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
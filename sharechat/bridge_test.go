@@ -0,0 +1,143 @@
+//go:build unit || all
+
+package sharechat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deployment is a cluster built on the memory repositories & Queue
+type deployment struct {
+	sharechat.Cluster
+	roomRepo    *memory.RoomRepo
+	memberRepo  *memory.MemberRepo
+	messageRepo *memory.MessageRepo
+	queue       *memory.Queue
+}
+
+func newDeployment(name string) *deployment {
+	d := &deployment{messageRepo: memory.NewMessageRepo(), queue: memory.NewQueue()}
+	d.memberRepo = memory.NewMemberRepo(d.messageRepo)
+	d.roomRepo = memory.NewRoomRepo(d.memberRepo, d.messageRepo)
+	d.Cluster = sharechat.Cluster{
+		Name: name,
+		Controller: sharechat.NewController(sharechat.NewControllerInput{
+			RoomRepo:    d.roomRepo,
+			MemberRepo:  d.memberRepo,
+			MessageRepo: d.messageRepo,
+			OutboxRepo:  d.messageRepo,
+			Queue:       d.queue,
+		}),
+	}
+	return d
+}
+
+func TestBridge(t *testing.T) {
+	eu, us := newDeployment("eu"), newDeployment("us")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	room := sharechat.NewRoom("bridged")
+	require.NoError(t, eu.roomRepo.InsertRoom(ctx, room))
+	alice := sharechat.NewMember("alice", room.ID, nil)
+	_, err := eu.memberRepo.InsertMember(ctx, *alice)
+	require.NoError(t, err)
+
+	bridge := sharechat.NewBridge(eu.Cluster, us.Cluster)
+	defer bridge.Close()
+	require.ErrorIs(t, bridge.BridgeRoom(ctx, "missing"), sharechat.ErrRoomNotFound)
+	require.NoError(t, bridge.BridgeRoom(ctx, room.ID))
+
+	replicated, err := us.Controller.GetRoom(ctx, room.ID)
+	require.NoError(t, err, "the room should be created in the other cluster")
+	assert.Equal(t, room.Name, replicated.RoomName)
+	require.Len(t, replicated.Members, 1, "existing members should be replicated")
+	assert.Equal(t, alice.ID, replicated.Members[0].ID)
+
+	euSubscription, err := eu.queue.Subscribe(ctx, room.ID)
+	require.NoError(t, err)
+	defer euSubscription.Close()
+	usSubscription, err := us.queue.Subscribe(ctx, room.ID)
+	require.NoError(t, err)
+	defer usSubscription.Close()
+
+	// bob joins in us & says hello, the way a Controller would
+	bob := sharechat.NewMember("bob", room.ID, nil)
+	joined, err := us.memberRepo.InsertMember(ctx, *bob)
+	require.NoError(t, err)
+	require.NoError(t, us.queue.Publish(ctx, *joined))
+	hello, err := us.messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*bob, []byte("hello")))
+	require.NoError(t, err)
+	require.NoError(t, us.queue.Publish(ctx, *hello))
+
+	relayedJoin := receiveMessage(t, euSubscription)
+	assert.Equal(t, sharechat.MemberJoined, relayedJoin.Type)
+	assert.Equal(t, bob.ID, relayedJoin.MemberID)
+	assert.Equal(t, "us", relayedJoin.Cluster, "relayed messages should be tagged with their cluster")
+
+	relayedHello := receiveMessage(t, euSubscription)
+	assert.Equal(t, hello.ID, relayedHello.ID)
+	assert.Equal(t, "us", relayedHello.Cluster)
+
+	members, err := eu.Controller.GetRoom(ctx, room.ID)
+	require.NoError(t, err)
+	assert.Len(t, members.Members, 2, "members joining should be replicated")
+	stored, err := eu.Controller.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	require.NoError(t, err)
+	assert.Contains(t, messageIDs(stored), hello.ID, "relayed messages should be stored")
+
+	// us sees its own messages once: what was relayed to eu is not relayed back
+	assert.Equal(t, joined.ID, receiveMessage(t, usSubscription).ID)
+	assert.Equal(t, hello.ID, receiveMessage(t, usSubscription).ID)
+
+	// alice answers from eu
+	answer, err := eu.messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*alice, []byte("hi bob")))
+	require.NoError(t, err)
+	require.NoError(t, eu.queue.Publish(ctx, *answer))
+	assert.Equal(t, answer.ID, receiveMessage(t, usSubscription).ID)
+	assert.Equal(t, answer.ID, receiveMessage(t, euSubscription).ID)
+
+	select {
+	case message := <-usSubscription.Messages():
+		t.Fatalf("message %s was relayed back", message.ID)
+	case message := <-euSubscription.Messages():
+		t.Fatalf("message %s was relayed back", message.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	bridge.UnbridgeRoom(room.ID)
+	unrelayed, err := us.messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*bob, []byte("anyone?")))
+	require.NoError(t, err)
+	require.NoError(t, us.queue.Publish(ctx, *unrelayed))
+	assert.Equal(t, unrelayed.ID, receiveMessage(t, usSubscription).ID)
+	select {
+	case message := <-euSubscription.Messages():
+		t.Fatalf("message %s was relayed after the room was unbridged", message.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func receiveMessage(t *testing.T, subscription sharechat.Subscription) sharechat.Message {
+	select {
+	case message := <-subscription.Messages():
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+		return sharechat.Message{}
+	}
+}
+
+func messageIDs(messages []sharechat.Message) []string {
+	ids := []string{}
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
@@ -3,4 +3,5 @@ package sharechat
 type Connection interface {
 	WriteMessage(Message) error
 	ReadBytes() ([]byte, error)
+	Close() error
 }
@@ -2,28 +2,66 @@ package sharechat
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"time"
 )
 
+// notifyTimeout bounds how long Publish waits to tell a member that its message failed
+const notifyTimeout = 5 * time.Second
+
 type NewControllerInput struct {
 	RoomRepo    RoomRepository
 	MemberRepo  MemberRepository
 	MessageRepo MessageRepository
 	Queue       Queue
+	// OutboxRepo should be set to the outbox of the MessageRepo, since it adds every message it
+	// stores to the outbox; the Controller removes messages once they are published & RelayOutbox
+	// retries the rest. If nil, stored messages are published directly without any retries & the
+	// MessageRepo's outbox is never drained. Edits, deletions & reactions are never added to the
+	// outbox, so they are lost if publishing them fails.
+	OutboxRepo  OutboxRepository
 	Healthcheck func(context.Context) error
+	// RoomTTL is how long a room may go without any messages before ReapRooms deletes it.
+	// A zero value disables room expiry.
+	RoomTTL time.Duration
+	// Retention is the global retention policy enforced by CompactMessages;
+	// rooms may override it with their own. A zero value keeps messages forever.
+	Retention RetentionPolicy
+	// DedupWindow & DedupCapacity bound the IDs of recently delivered messages remembered per room
+	// to drop messages delivered twice; zero values default to 5 minutes & 1000 messages.
+	DedupWindow   time.Duration
+	DedupCapacity int
 }
 
 func NewController(input NewControllerInput) *Controller {
+	if input.OutboxRepo == nil {
+		input.OutboxRepo = directOutbox{}
+	}
+	if input.DedupWindow <= 0 {
+		input.DedupWindow = defaultDedupWindow
+	}
+	if input.DedupCapacity <= 0 {
+		input.DedupCapacity = defaultDedupCapacity
+	}
+
 	return &Controller{
-		roomRepo:    input.RoomRepo,
-		memberRepo:  input.MemberRepo,
-		messageRepo: input.MessageRepo,
-		queue:       input.Queue,
-		generator:   NewGenerator(),
-		Healthcheck: input.Healthcheck,
-		mu:          new(sync.Mutex),
-		roomCache:   make(map[string]*Room),
+		roomRepo:      input.RoomRepo,
+		memberRepo:    input.MemberRepo,
+		messageRepo:   input.MessageRepo,
+		queue:         input.Queue,
+		outboxRepo:    input.OutboxRepo,
+		generator:     NewGenerator(),
+		Healthcheck:   input.Healthcheck,
+		mu:            new(sync.Mutex),
+		roomCache:     make(map[string]*Room),
+		roomTTL:       input.RoomTTL,
+		retention:     input.Retention,
+		recovering:    make(map[string]struct{}),
+		dedupWindow:   input.DedupWindow,
+		dedupCapacity: input.DedupCapacity,
 	}
 }
 
@@ -32,12 +70,21 @@ type Controller struct {
 	memberRepo  MemberRepository
 	messageRepo MessageRepository
 	queue       Queue
+	outboxRepo  OutboxRepository
 
 	generator   Generator
 	Healthcheck func(context.Context) error
 
 	mu        *sync.Mutex
 	roomCache map[string]*Room
+	// recovering holds the rooms whose subscription is being recovered
+	recovering map[string]struct{}
+
+	roomTTL   time.Duration
+	retention RetentionPolicy
+
+	dedupWindow   time.Duration
+	dedupCapacity int
 }
 
 func (c *Controller) CreateRoom(ctx context.Context, callbackFn ...func(*Message)) (*Room, error) {
@@ -68,24 +115,32 @@ func (c *Controller) CreateRoom(ctx context.Context, callbackFn ...func(*Message
 	return room, nil
 }
 
+// ServeRoom adds a Member reading & writing messages through the connection to the room
 func (c *Controller) ServeRoom(ctx context.Context, roomID string, connection Connection) error {
+	_, err := c.JoinRoom(ctx, roomID, connection)
+	return err
+}
+
+// JoinRoom is ServeRoom returning the Member that joined, for transports that need to know who
+// the connection belongs to. The Member is also returned with an ErrFailedToPublish, since it joined.
+func (c *Controller) JoinRoom(ctx context.Context, roomID string, connection Connection) (*Member, error) {
 	var room *Room
 	cacheResult, ok := c.getRoomFromCache(roomID)
 	if !ok {
 		repoResult, err := c.roomRepo.GetRoom(ctx, roomID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		room = repoResult
 		c.addRoomToCache(room)
 		if err := c.startRoom(ctx, room); err != nil {
 			c.deleteRoomFromCache(room.ID)
-			return err
+			return nil, err
 		}
 		if err := c.Subscribe(ctx, room); err != nil {
 			room.CloseInbound()
 			c.deleteRoomFromCache(room.ID)
-			return err
+			return nil, err
 		}
 	} else {
 		room = cacheResult
@@ -99,7 +154,7 @@ func (c *Controller) ServeRoom(ctx context.Context, roomID string, connection Co
 		// Do not allow the member to Listen if the goroutine
 		// does not start within our context deadline.
 		member.CloseInbound()
-		return err
+		return nil, err
 	}
 
 	go c.Publish(ctx, member)
@@ -112,14 +167,14 @@ func (c *Controller) ServeRoom(ctx context.Context, roomID string, connection Co
 		// goroutine does not start within our context deadline.
 		// This also halts the Publish goroutine started previously.
 		member.StopBroadcast()
-		return err
+		return nil, err
 	}
 
 	message, err := c.memberRepo.InsertMember(ctx, *member)
 	if err != nil {
 		member.CloseInbound()
 		member.StopBroadcast()
-		return err
+		return nil, err
 	}
 
 	// don't allow the Member to broadcast until we return
@@ -129,10 +184,86 @@ func (c *Controller) ServeRoom(ctx context.Context, roomID string, connection Co
 
 	room.AddMember(member)
 
-	if err := c.queue.Publish(ctx, *message); err != nil {
+	if err := c.publishStored(ctx, *message); err != nil {
 		// At this point the member & joined message have been saved in the database,
 		// so we don't stop the goroutines we've started.
-		return &ErrFailedToPublish{err: err}
+		return member, &ErrFailedToPublish{err: err}
+	}
+
+	return member, nil
+}
+
+// store applies a message sent by a member to the repositories, returning the message to publish
+// & whether it was stored, in which case it is in the outbox. Edits, deletions & reactions
+// change other messages and are not stored themselves, while typing & acknowledgements are only published.
+func (c *Controller) store(ctx context.Context, message Message) (*Message, bool, error) {
+	switch message.Type {
+	case MessageEdited:
+		result, err := c.messageRepo.UpdateMessage(ctx, message)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update message %s: %w", message.TargetID, err)
+		}
+
+		message.Message = result.Message
+		message.EditedAt = result.EditedAt
+		message.Sent = *result.EditedAt
+		return &message, false, nil
+	case MessageDeleted:
+		if _, err := c.messageRepo.DeleteMessage(ctx, message); err != nil {
+			return nil, false, fmt.Errorf("failed to delete message %s: %w", message.TargetID, err)
+		}
+
+		message.Sent = time.Now()
+		return &message, false, nil
+	case ReactionAdded, ReactionRemoved:
+		var err error
+		if message.Type == ReactionAdded {
+			err = c.messageRepo.AddReaction(ctx, message)
+		} else {
+			err = c.messageRepo.RemoveReaction(ctx, message)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update reactions for message %s: %w", message.TargetID, err)
+		}
+
+		message.Sent = time.Now()
+		return &message, false, nil
+	case MemberTyping, MessageAcked:
+		message.Sent = time.Now()
+		return &message, false, nil
+	default:
+		result, err := c.messageRepo.InsertMessage(ctx, message)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to insert message: %w", err)
+		}
+		return result, true, nil
+	}
+}
+
+// isRejected reports whether storing a message failed because it was invalid,
+// rather than because of the repository; rejections are not logged
+func isRejected(err error) bool {
+	return errors.Is(err, ErrMessageNotFound) || errors.Is(err, ErrDuplicateReaction) || errors.Is(err, ErrReactionNotFound)
+}
+
+// publishResult publishes a message returned by store
+func (c *Controller) publishResult(ctx context.Context, message Message, stored bool) error {
+	if stored {
+		return c.publishStored(ctx, message)
+	}
+	return c.queue.Publish(ctx, message)
+}
+
+// publishStored publishes a message that has already been persisted & removes it from the outbox.
+// A failed publish is left in the outbox for RelayOutbox to retry, so no error is returned.
+func (c *Controller) publishStored(ctx context.Context, message Message) error {
+	if err := c.queue.Publish(ctx, message); err != nil {
+		log.Printf("failed to publish message %s, leaving it for the outbox relay: %v", message.ID, err)
+		return nil
+	}
+
+	if err := c.outboxRepo.MarkPublished(ctx, message.ID); err != nil {
+		log.Printf("failed to remove message %s from the outbox: %v", message.ID, err)
 	}
 
 	return nil
@@ -150,6 +281,15 @@ func (c *Controller) deleteRoomFromCache(roomID string) {
 	c.mu.Unlock()
 }
 
+// evictRoomFromCache removes the room from the cache only if it has not already been replaced
+func (c *Controller) evictRoomFromCache(room *Room) {
+	c.mu.Lock()
+	if cached, ok := c.roomCache[room.ID]; ok && cached == room {
+		delete(c.roomCache, room.ID)
+	}
+	c.mu.Unlock()
+}
+
 func (c *Controller) getRoomFromCache(roomID string) (*Room, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -185,20 +325,25 @@ func (c *Controller) Publish(ctx context.Context, member *Member) {
 					return
 				}
 
-				if err := c.queue.Publish(ctx, *message); err != nil {
+				if err := c.publishStored(ctx, *message); err != nil {
 					log.Printf("failed to publish member %s left: %v", member.ID, err)
 				}
 				return
+			case ProtocolError:
+				// only the member that sent the envelope is told it was rejected
+				c.notify(member, message)
 			default:
-				result, err := c.messageRepo.InsertMessage(ctx, message)
+				result, stored, err := c.store(ctx, message)
 				if err != nil {
-					log.Printf("failed to insert message: %v", err)
-					member.inbound <- NewSendFailedMessage(*member)
+					if !isRejected(err) {
+						log.Print(err)
+					}
+					c.notify(member, NewSendFailedMessage(*member))
 					break
 				}
 
-				if err := c.queue.Publish(ctx, *result); err != nil {
-					log.Printf("failed to publish message: %v", err)
+				if err := c.publishResult(ctx, *result, stored); err != nil {
+					log.Printf("failed to publish %s message %s: %v", result.Type, result.ID, err)
 				}
 			}
 		case <-member.stopBroadcast:
@@ -207,36 +352,264 @@ func (c *Controller) Publish(ctx context.Context, member *Member) {
 	}
 }
 
+// notify sends a message to the member whose message caused it, giving up if the member stops
+// broadcasting or its connection is not written to within notifyTimeout, so that Publish never blocks
+// on a member that has gone away
+func (c *Controller) notify(member *Member, message Message) {
+	timer := time.NewTimer(notifyTimeout)
+	defer timer.Stop()
+
+	select {
+	case member.inbound <- message:
+	case <-member.stopBroadcast:
+	case <-timer.C:
+		log.Printf("failed to notify member %s of %s message: %v", member.ID, message.Type, ErrSendTimedOut)
+	}
+}
+
 func (c *Controller) Subscribe(ctx context.Context, room *Room) error {
-	fn, err := c.queue.Subscribe(ctx, room.ID)
+	subscription, err := c.queue.Subscribe(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+
+	// remember the newest stored message, so anything lost along with
+	// the subscription can be backfilled from the repository
+	lastSeq, err := c.lastSeq(ctx, room.ID)
 	if err != nil {
+		subscription.Close()
 		return err
 	}
 
-	controller := make(chan Message)
-	done := make(chan struct{})
-	ready := make(chan struct{})
-	// start goroutine to funnel messages from the queue to controller
-	go fn(controller, done, ready)
-	<-ready
-	go func(controller chan Message, done, ready chan struct{}) {
-		ready <- struct{}{}
+	// funnel messages from the queue to the room until it stops, then
+	// unsubscribe & evict the room from the cache
+	go func() {
+		defer c.evictRoomFromCache(room)
+		defer func() {
+			if subscription != nil {
+				subscription.Close()
+			}
+		}()
+
+		// messages may be delivered more than once by the Queue, the outbox relay & backfills
+		recent := newRecentMessages(c.dedupWindow, c.dedupCapacity)
+		deliver := func(message Message) bool {
+			if recent.Seen(message.ID, time.Now()) {
+				duplicateMessages.Add(1)
+				return true
+			}
+			if message.Seq > lastSeq {
+				lastSeq = message.Seq
+			}
+
+			select {
+			case room.inbound <- message:
+				return true
+			case <-room.shutdown:
+				return false
+			}
+		}
+
 		for {
 			select {
 			case <-room.shutdown:
-				// shut down goroutine funneling messages from queue to controller
-				done <- struct{}{}
 				return
-			case message := <-controller:
-				room.inbound <- message
+			case message, ok := <-subscription.Messages():
+				if ok {
+					if !deliver(message) {
+						return
+					}
+					continue
+				}
+
+				log.Printf("subscription to room %s failed, subscribing again: %v", room.ID, subscription.Err())
+				subscription.Close()
+
+				var backfill []Message
+				subscription, backfill = c.recoverSubscription(room, lastSeq)
+				if subscription == nil {
+					// unless it already stopped, the room can no longer receive messages,
+					// so stop it; its members reconnect & subscribe again
+					select {
+					case <-room.shutdown:
+					default:
+						room.CloseInbound()
+					}
+					return
+				}
+
+				log.Printf("recovered subscription to room %s, backfilling %d messages", room.ID, len(backfill))
+				for _, message := range backfill {
+					if !deliver(message) {
+						return
+					}
+				}
 			}
 		}
-	}(controller, done, ready)
-	<-ready
+	}()
 
 	return nil
 }
 
+// DeleteRoom deletes the room along with its members & messages, then notifies every
+// Controller serving the room so that connected members are closed and the room is stopped.
+func (c *Controller) DeleteRoom(ctx context.Context, roomID string) error {
+	if err := c.roomRepo.DeleteRoom(ctx, roomID); err != nil {
+		return err
+	}
+
+	if err := c.queue.Publish(ctx, NewRoomExpiredMessage(roomID)); err != nil {
+		return &ErrFailedToPublish{err: err}
+	}
+
+	return nil
+}
+
+// ReapRooms deletes rooms that have been inactive for longer than the configured RoomTTL,
+// checking every interval until ctx is cancelled. It returns immediately if RoomTTL is not set.
+func (c *Controller) ReapRooms(ctx context.Context, interval time.Duration) {
+	if c.roomTTL <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.reapInactiveRooms(ctx, interval)
+		}
+	}
+}
+
+func (c *Controller) reapInactiveRooms(ctx context.Context, timeout time.Duration) {
+	roomIDs, err := c.roomRepo.GetInactiveRooms(ctx, time.Now().Add(-c.roomTTL))
+	if err != nil {
+		log.Printf("failed to get inactive rooms: %v", err)
+		return
+	}
+
+	for _, roomID := range roomIDs {
+		deleteCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := c.DeleteRoom(deleteCtx, roomID)
+		cancel()
+
+		var publishErr *ErrFailedToPublish
+		switch {
+		case err == nil:
+			log.Printf("deleted inactive room %s", roomID)
+		case errors.Is(err, ErrRoomNotFound):
+			// another Controller reaped the room first
+		case errors.As(err, &publishErr):
+			log.Printf("deleted inactive room %s, but failed to notify members: %v", roomID, publishErr)
+		default:
+			log.Printf("failed to delete inactive room %s: %v", roomID, err)
+		}
+	}
+}
+
+// RelayOutbox publishes messages left in the outbox, checking every interval until ctx is cancelled.
+// Messages are only relayed once they have been pending for an interval, giving the Controller
+// that saved them the chance to publish them first. Failed publishes are retried with exponential
+// backoff.
+func (c *Controller) RelayOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.relayOutbox(ctx, interval)
+		}
+	}
+}
+
+func (c *Controller) relayOutbox(ctx context.Context, timeout time.Duration) {
+	now := time.Now()
+	messages, err := c.outboxRepo.ClaimPendingMessages(ctx, now.Add(-timeout), now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		log.Printf("failed to claim outbox messages: %v", err)
+		return
+	}
+
+	for _, message := range messages {
+		publishCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := c.queue.Publish(publishCtx, message.Message)
+		cancel()
+
+		if err != nil {
+			backoff := outboxBackoff(message.Attempts)
+			log.Printf("failed to relay message %s (attempt %d), retrying in %s: %v", message.ID, message.Attempts+1, backoff, err)
+			if err := c.outboxRepo.MarkFailed(ctx, message.ID, time.Now().Add(backoff)); err != nil {
+				log.Printf("failed to reschedule outbox message %s: %v", message.ID, err)
+			}
+			continue
+		}
+
+		if err := c.outboxRepo.MarkPublished(ctx, message.ID); err != nil {
+			log.Printf("failed to remove message %s from the outbox: %v", message.ID, err)
+		}
+	}
+}
+
+// CompactMessages purges messages exceeding the retention policy of their room, checking every
+// interval until ctx is cancelled. Rooms without an override of their own use the global policy.
+func (c *Controller) CompactMessages(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.compactMessages(ctx)
+		}
+	}
+}
+
+func (c *Controller) compactMessages(ctx context.Context) {
+	compactionRuns.Add(1)
+	start := time.Now()
+
+	total, batches := 0, 0
+	for {
+		purged, err := c.roomRepo.PurgeMessages(ctx, c.retention, compactionBatchSize)
+		total += purged
+		purgedMessages.Add(int64(purged))
+		if err != nil {
+			compactionErrors.Add(1)
+			log.Printf("failed to purge messages after purging %d: %v", total, err)
+			return
+		}
+
+		batches++
+		if purged < compactionBatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("purged %d messages in %d batches (%s)", total, batches, time.Since(start))
+	}
+}
+
+func (c *Controller) GetRoomRetention(ctx context.Context, roomID string) (*RetentionPolicy, error) {
+	return c.roomRepo.GetRoomRetention(ctx, roomID)
+}
+
+// SetRoomRetention overrides the global retention policy for a room.
+// Limits left at zero fall back to the global policy.
+func (c *Controller) SetRoomRetention(ctx context.Context, roomID string, policy RetentionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	return c.roomRepo.SetRoomRetention(ctx, roomID, policy)
+}
+
 type GetRoomResponse struct {
 	RoomID   string   `json:"roomId"`
 	RoomName string   `json:"roomName"`
@@ -264,3 +637,11 @@ func (c *Controller) GetMessages(ctx context.Context, options GetMessageOptions)
 
 	return c.messageRepo.GetMessages(ctx, options)
 }
+
+func (c *Controller) SearchMessages(ctx context.Context, options SearchMessageOptions) ([]SearchResult, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.messageRepo.SearchMessages(ctx, options)
+}
@@ -4,6 +4,10 @@ package sharechat_test
 
 import (
 	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"runtime"
 	"testing"
 	"time"
 
@@ -11,18 +15,20 @@ import (
 	"github.com/soggycactus/sharechat.dev/sharechat/memory"
 	"github.com/soggycactus/sharechat.dev/sharechat/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestController(t *testing.T) {
-	roomRepo := memory.NewRoomRepo()
 	messageRepo := memory.NewMessageRepo()
 	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
 
 	controller := sharechat.NewController(
 		sharechat.NewControllerInput{
 			RoomRepo:    roomRepo,
 			MemberRepo:  memberRepo,
 			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
 			Queue:       memory.NewQueue(),
 		},
 	)
@@ -57,3 +63,754 @@ func TestController(t *testing.T) {
 	assert.Equal(t, 1, len(room.Members()), "room should have one member")
 	assert.True(t, beforeSent.Before(chat.Sent), "message timestamp should be more recent")
 }
+
+func TestDeleteRoom(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       memory.NewQueue(),
+		},
+	)
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer fn()
+
+	roomProcessedMessage := make(chan sharechat.Message)
+	room, err := controller.CreateRoom(ctx, func(m *sharechat.Message) {
+		roomProcessedMessage <- *m
+	})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	connection := mock.NewConnection().
+		WithWriteMessageResult(nil).
+		WithReadBytesResult([]byte("hello world"), nil)
+
+	err = controller.ServeRoom(ctx, room.ID, connection)
+	if err != nil {
+		t.Fatalf("failed to serve room: %v", err)
+	}
+
+	// drain the joined & chat messages
+	<-roomProcessedMessage
+	<-roomProcessedMessage
+
+	err = controller.DeleteRoom(ctx, room.ID)
+	if err != nil {
+		t.Fatalf("failed to delete room: %v", err)
+	}
+
+	expired := <-roomProcessedMessage
+	assert.Equal(t, sharechat.RoomExpired, expired.Type, "room should process the expired message")
+
+	select {
+	case <-room.Done():
+	case <-ctx.Done():
+		t.Fatal("room should be stopped")
+	}
+
+	select {
+	case <-connection.Closed():
+	case <-ctx.Done():
+		t.Fatal("member connection should be closed")
+	}
+
+	receivedExpired := false
+	for _, message := range connection.InboundMessages() {
+		if message.Type == sharechat.RoomExpired {
+			receivedExpired = true
+		}
+	}
+	assert.True(t, receivedExpired, "member should be notified that the room expired")
+
+	_, err = controller.GetRoom(ctx, room.ID)
+	assert.ErrorIs(t, err, sharechat.ErrRoomNotFound, "room should be deleted")
+
+	messages, err := controller.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, messages, "messages should be deleted with the room")
+
+	err = controller.DeleteRoom(ctx, room.ID)
+	assert.ErrorIs(t, err, sharechat.ErrRoomNotFound, "deleting a missing room should fail")
+}
+
+func TestReapRooms(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       memory.NewQueue(),
+			RoomTTL:     time.Millisecond,
+		},
+	)
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer fn()
+
+	room, err := controller.CreateRoom(ctx)
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	go controller.ReapRooms(ctx, 10*time.Millisecond)
+
+	select {
+	case <-room.Done():
+	case <-ctx.Done():
+		t.Fatal("inactive room should be reaped")
+	}
+
+	_, err = controller.GetRoom(ctx, room.ID)
+	assert.ErrorIs(t, err, sharechat.ErrRoomNotFound, "room should be deleted")
+}
+
+func TestEditAndDeleteMessage(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       memory.NewQueue(),
+		},
+	)
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer fn()
+
+	roomProcessedMessage := make(chan sharechat.Message)
+	room, err := controller.CreateRoom(ctx, func(m *sharechat.Message) {
+		roomProcessedMessage <- *m
+	})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	author := mock.NewConnection().WithReadBytesResult([]byte("helo world"), nil)
+	if err := controller.ServeRoom(ctx, room.ID, author); err != nil {
+		t.Fatalf("failed to serve room: %v", err)
+	}
+	<-roomProcessedMessage
+	chat := <-roomProcessedMessage
+	require.Equal(t, sharechat.Chat, chat.Type, "second message should be chat type")
+
+	other := mock.NewConnection()
+	if err := controller.ServeRoom(ctx, room.ID, other); err != nil {
+		t.Fatalf("failed to serve room: %v", err)
+	}
+	<-roomProcessedMessage
+
+	// another member cannot edit the author's message
+	other.Send([]byte(fmt.Sprintf(`{"type":"edit","id":"%s","message":"hijacked"}`, chat.ID)))
+	assert.Eventually(t, func() bool {
+		for _, message := range other.InboundMessages() {
+			if message.Type == sharechat.SendFailed {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond, "other member should be told the edit failed")
+
+	author.Send([]byte(fmt.Sprintf(`{"type":"edit","id":"%s","message":"hello world"}`, chat.ID)))
+	edited := <-roomProcessedMessage
+	assert.Equal(t, sharechat.MessageEdited, edited.Type, "room should broadcast the edit")
+	assert.Equal(t, chat.ID, edited.TargetID, "edit should reference the chat message")
+	assert.Equal(t, "hello world", edited.Message, "edit should have the new content")
+	assert.NotNil(t, edited.EditedAt, "edit should have an edited timestamp")
+
+	other.Send([]byte(fmt.Sprintf(`{"type":"delete","id":"%s"}`, chat.ID)))
+	author.Send([]byte(fmt.Sprintf(`{"type":"delete","id":"%s"}`, chat.ID)))
+	deleted := <-roomProcessedMessage
+	assert.Equal(t, sharechat.MessageDeleted, deleted.Type, "room should broadcast the deletion")
+	assert.Equal(t, chat.ID, deleted.TargetID, "deletion should reference the chat message")
+	assert.Eventually(t, func() bool {
+		_, ok := author.InboundMessages()[deleted.ID]
+		return ok
+	}, time.Second, 10*time.Millisecond, "author should receive the deletion")
+
+	messages, err := controller.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range messages {
+		assert.NotEqual(t, chat.ID, message.ID, "chat message should be deleted")
+	}
+}
+
+func TestReactions(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       memory.NewQueue(),
+		},
+	)
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer fn()
+
+	roomProcessedMessage := make(chan sharechat.Message)
+	room, err := controller.CreateRoom(ctx, func(m *sharechat.Message) {
+		roomProcessedMessage <- *m
+	})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	connection := mock.NewConnection().WithReadBytesResult([]byte("hello world"), nil)
+	if err := controller.ServeRoom(ctx, room.ID, connection); err != nil {
+		t.Fatalf("failed to serve room: %v", err)
+	}
+	<-roomProcessedMessage
+	chat := <-roomProcessedMessage
+
+	connection.Send([]byte(fmt.Sprintf(`{"type":"react","id":"%s","emoji":"👍"}`, chat.ID)))
+	reacted := <-roomProcessedMessage
+	assert.Equal(t, sharechat.ReactionAdded, reacted.Type, "room should broadcast the reaction")
+	assert.Equal(t, chat.ID, reacted.TargetID, "reaction should reference the chat message")
+	assert.Equal(t, "👍", reacted.Emoji, "reaction should have the emoji")
+
+	messages, err := controller.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, messages, 1)
+	assert.Equal(t, map[string]int{"👍": 1}, messages[0].Reactions, "reaction should be counted")
+
+	connection.Send([]byte(fmt.Sprintf(`{"type":"unreact","id":"%s","emoji":"👍"}`, chat.ID)))
+	unreacted := <-roomProcessedMessage
+	assert.Equal(t, sharechat.ReactionRemoved, unreacted.Type, "room should broadcast the removed reaction")
+
+	messages, err = controller.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, messages, 1)
+	assert.Empty(t, messages[0].Reactions, "reaction should be removed")
+}
+
+func TestEnvelopes(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       memory.NewQueue(),
+		},
+	)
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer fn()
+
+	roomProcessedMessage := make(chan sharechat.Message)
+	room, err := controller.CreateRoom(ctx, func(m *sharechat.Message) {
+		roomProcessedMessage <- *m
+	})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	connection := mock.NewConnection().WithReadBytesResult([]byte(`{"v":1,"op":"chat","payload":{"message":"hello world"}}`), nil)
+	if err := controller.ServeRoom(ctx, room.ID, connection); err != nil {
+		t.Fatalf("failed to serve room: %v", err)
+	}
+	<-roomProcessedMessage
+	chat := <-roomProcessedMessage
+	assert.Equal(t, sharechat.Chat, chat.Type, "room should broadcast the chat message")
+	assert.Equal(t, "hello world", chat.Message, "chat message should have the payload's message")
+
+	connection.Send([]byte(`{"v":1,"op":"typing"}`))
+	typing := <-roomProcessedMessage
+	assert.Equal(t, sharechat.MemberTyping, typing.Type, "room should broadcast typing")
+
+	// protocol errors are written back to the member without closing its connection
+	connection.Send([]byte(`{"v":1,"op":"dance"}`))
+	connection.Send([]byte(fmt.Sprintf(`{"v":1,"op":"ack","payload":{"id":"%s"}}`, chat.ID)))
+	acked := <-roomProcessedMessage
+	assert.Equal(t, sharechat.MessageAcked, acked.Type, "room should broadcast the acknowledgement, not the error")
+	assert.Equal(t, chat.ID, acked.TargetID, "acknowledgement should reference the chat message")
+
+	var protocolErr *sharechat.Message
+	for _, message := range connection.InboundMessages() {
+		if message.Type == sharechat.ProtocolError {
+			protocolErr = message
+		}
+	}
+	require.NotNil(t, protocolErr, "member should be sent the error")
+	assert.Equal(t, sharechat.UnknownOp, protocolErr.Code, "error should have its code")
+	select {
+	case <-connection.Closed():
+		t.Fatal("connection should stay open")
+	default:
+	}
+
+	messages, err := controller.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, messages, 2, "only the join & chat message should be stored")
+}
+
+func TestRelayOutbox(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	queue := mock.NewQueue().WithPublishResult(errors.New("queue is down"))
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			Queue:       queue,
+			OutboxRepo:  messageRepo,
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	room, err := controller.CreateRoom(ctx)
+	require.NoError(t, err)
+
+	connection := mock.NewConnection().WithWriteMessageResult(nil)
+	err = controller.ServeRoom(ctx, room.ID, connection)
+	require.NoError(t, err, "failed publishes should be left for the outbox relay")
+	assert.Empty(t, queue.Published(), "joined message should not have been published yet")
+
+	pending, err := messageRepo.ClaimPendingMessages(ctx, time.Now(), time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "joined message should be in the outbox")
+	assert.Equal(t, sharechat.MemberJoined, pending[0].Type)
+
+	relayCtx, stopRelay := context.WithCancel(ctx)
+	defer stopRelay()
+	go controller.RelayOutbox(relayCtx, 10*time.Millisecond)
+
+	queue.WithPublishResult(nil)
+	assert.Eventually(t, func() bool {
+		published := queue.Published()
+		return len(published) == 1 && published[0].ID == pending[0].ID
+	}, 4*time.Second, 10*time.Millisecond, "relay should publish the joined message once the queue recovers")
+
+	pending, err = messageRepo.ClaimPendingMessages(ctx, time.Now(), time.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "published messages should be removed from the outbox")
+}
+
+func TestNewControllerWithoutOutbox(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    memory.NewRoomRepo(memberRepo, messageRepo),
+		MemberRepo:  memberRepo,
+		MessageRepo: messageRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer fn()
+
+	roomProcessedMessage := make(chan sharechat.Message)
+	room, err := controller.CreateRoom(ctx, func(m *sharechat.Message) {
+		roomProcessedMessage <- *m
+	})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	connection := mock.NewConnection().
+		WithWriteMessageResult(nil).
+		WithReadBytesResult([]byte("hello world"), nil)
+
+	err = controller.ServeRoom(ctx, room.ID, connection)
+	if err != nil {
+		t.Fatalf("failed to serve room: %v", err)
+	}
+
+	// messages are published directly
+	assert.Equal(t, sharechat.MemberJoined, (<-roomProcessedMessage).Type)
+	assert.Equal(t, sharechat.Chat, (<-roomProcessedMessage).Type)
+
+	// & there is nothing for the relay to retry
+	relayCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	controller.RelayOutbox(relayCtx, 10*time.Millisecond)
+
+	select {
+	case message := <-roomProcessedMessage:
+		t.Fatalf("message %s should not be relayed", message.ID)
+	default:
+	}
+}
+
+func TestCompactMessages(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       mock.NewQueue(),
+			Retention:   sharechat.RetentionPolicy{MaxMessages: 3},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rooms := []*sharechat.Room{sharechat.NewRoom("global"), sharechat.NewRoom("override")}
+	for _, room := range rooms {
+		require.NoError(t, roomRepo.InsertRoom(ctx, room))
+
+		member := sharechat.NewMember("test", room.ID, nil)
+		for i := 0; i < 5; i++ {
+			_, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte(fmt.Sprint(i))))
+			require.NoError(t, err)
+		}
+	}
+
+	err := controller.SetRoomRetention(ctx, rooms[1].ID, sharechat.RetentionPolicy{MaxMessages: -1})
+	assert.ErrorIs(t, err, sharechat.ErrInvalidOptions, "negative limits should be rejected")
+
+	err = controller.SetRoomRetention(ctx, rooms[1].ID, sharechat.RetentionPolicy{MaxMessages: 1})
+	require.NoError(t, err)
+
+	retention, err := controller.GetRoomRetention(ctx, rooms[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, sharechat.RetentionPolicy{MaxMessages: 1}, *retention)
+
+	purged, err := roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{MaxMessages: 3}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged, "should purge at most a batch of messages")
+
+	go controller.CompactMessages(ctx, 10*time.Millisecond)
+
+	for room, kept := range map[string]int{rooms[0].ID: 3, rooms[1].ID: 1} {
+		assert.Eventually(t, func() bool {
+			messages, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room})
+			return err == nil && len(messages) == kept && messages[0].Message == "4"
+		}, 4*time.Second, 10*time.Millisecond, "should keep the %d most recent messages of room %s", kept, room)
+	}
+}
+
+func TestRoomShutdownReleasesSubscription(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       memory.NewQueue(),
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	goroutines := runtime.NumGoroutine()
+
+	rooms := []*sharechat.Room{}
+	for i := 0; i < 10; i++ {
+		room, err := controller.CreateRoom(ctx)
+		require.NoError(t, err)
+		rooms = append(rooms, room)
+	}
+	assert.Greater(t, runtime.NumGoroutine(), goroutines, "rooms should be running")
+
+	for _, room := range rooms {
+		require.NoError(t, controller.DeleteRoom(ctx, room.ID))
+		<-room.Done()
+	}
+
+	// poll rather than use assert.Eventually, which runs the condition in a goroutine of its own
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "stopped rooms should release their subscriptions")
+}
+
+func TestSubscriptionFailureStopsRoom(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	queue := mock.NewQueue()
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       queue,
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	room, err := controller.CreateRoom(ctx)
+	require.NoError(t, err)
+
+	queue.WithSubscribeResult(sharechat.ErrQueueClosed)
+	queue.FailSubscriptions(sharechat.ErrQueueClosed)
+
+	select {
+	case <-room.Done():
+	case <-ctx.Done():
+		t.Fatal("room should stop once its subscription fails & the queue is closed")
+	}
+	queue.WithSubscribeResult(nil)
+
+	// the room is started & subscribed to again once a member reconnects
+	connection := mock.NewConnection().WithWriteMessageResult(nil)
+	require.NoError(t, controller.ServeRoom(ctx, room.ID, connection))
+}
+
+func TestSubscriptionRecovery(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	queue := mock.NewQueue()
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       queue,
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	received := make(chan sharechat.Message, 10)
+	room, err := controller.CreateRoom(ctx, func(m *sharechat.Message) { received <- *m })
+	require.NoError(t, err)
+
+	member := sharechat.NewMember("test", room.ID, nil)
+	before, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("before")))
+	require.NoError(t, err)
+
+	// the broker goes away: subscriptions are lost & cannot be re-established yet
+	queue.WithSubscribeResult(errors.New("connection refused"))
+	queue.FailSubscriptions(sharechat.ErrSubscriptionLost)
+
+	for deadline := time.Now().Add(5 * time.Second); len(controller.RecoveringRooms()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, []string{room.ID}, controller.RecoveringRooms(), "the room should be recovering")
+
+	// published by another Controller while this one was not subscribed
+	missed, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("missed")))
+	require.NoError(t, err)
+
+	queue.WithSubscribeResult(nil)
+
+	backfilled := []string{}
+	for len(backfilled) < 2 {
+		select {
+		case message := <-received:
+			backfilled = append(backfilled, message.ID)
+		case <-ctx.Done():
+			t.Fatalf("messages stored while the subscription was lost should be backfilled, got %v", backfilled)
+		}
+	}
+	assert.Equal(t, []string{before.ID, missed.ID}, backfilled,
+		"a room without messages when it subscribed should be backfilled from its first message")
+
+	for deadline := time.Now().Add(time.Second); len(controller.RecoveringRooms()) != 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Empty(t, controller.RecoveringRooms(), "the room should have recovered")
+
+	// lost again: only messages after the last one received are backfilled
+	queue.FailSubscriptions(sharechat.ErrSubscriptionLost)
+	after, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("after")))
+	require.NoError(t, err)
+
+	select {
+	case message := <-received:
+		assert.Equal(t, after.ID, message.ID)
+	case <-ctx.Done():
+		t.Fatal("messages stored after the last one received should be backfilled")
+	}
+	select {
+	case message := <-received:
+		t.Fatalf("message %s was delivered twice", message.Message)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	select {
+	case <-room.Done():
+		t.Fatal("the room should keep running through lost subscriptions")
+	default:
+	}
+}
+
+func TestSubscriptionRecoveryReplies(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	queue := mock.NewQueue()
+
+	controller := sharechat.NewController(
+		sharechat.NewControllerInput{
+			RoomRepo:    roomRepo,
+			MemberRepo:  memberRepo,
+			MessageRepo: messageRepo,
+			OutboxRepo:  messageRepo,
+			Queue:       queue,
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	room := sharechat.NewRoom("test")
+	require.NoError(t, roomRepo.InsertRoom(ctx, room))
+	member := sharechat.NewMember("test", room.ID, nil)
+	parent, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("parent")))
+	require.NoError(t, err)
+	// the newest message of the room when it subscribes is a reply
+	reply, err := messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, parent.ID, []byte("reply")))
+	require.NoError(t, err)
+
+	connection := mock.NewConnection().WithWriteMessageResult(nil)
+	require.NoError(t, controller.ServeRoom(ctx, room.ID, connection))
+
+	queue.WithSubscribeResult(errors.New("connection refused"))
+	queue.FailSubscriptions(sharechat.ErrSubscriptionLost)
+	for deadline := time.Now().Add(5 * time.Second); len(controller.RecoveringRooms()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	missed, err := messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, parent.ID, []byte("missed")))
+	require.NoError(t, err)
+	queue.WithSubscribeResult(nil)
+
+	for deadline := time.Now().Add(5 * time.Second); connection.InboundMessages()[missed.ID] == nil && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	inbound := connection.InboundMessages()
+	assert.NotNil(t, inbound[missed.ID], "replies stored while the subscription was lost should be backfilled")
+	assert.Nil(t, inbound[reply.ID], "replies stored before the room subscribed should not be backfilled")
+	assert.Nil(t, inbound[parent.ID], "messages stored before the room subscribed should not be backfilled")
+}
+
+func TestSubscriptionDeduplication(t *testing.T) {
+	newController := func(window time.Duration, capacity int) (*sharechat.Controller, *memory.Queue) {
+		messageRepo := memory.NewMessageRepo()
+		memberRepo := memory.NewMemberRepo(messageRepo)
+		queue := memory.NewQueue()
+		return sharechat.NewController(sharechat.NewControllerInput{
+			RoomRepo:      memory.NewRoomRepo(memberRepo, messageRepo),
+			MemberRepo:    memberRepo,
+			MessageRepo:   messageRepo,
+			OutboxRepo:    messageRepo,
+			Queue:         queue,
+			DedupWindow:   window,
+			DedupCapacity: capacity,
+		}), queue
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// startRoom returns a room & a func listing the IDs of the messages it received until it goes quiet
+	startRoom := func(controller *sharechat.Controller) (*sharechat.Room, func() []string) {
+		received := make(chan string, 10)
+		room, err := controller.CreateRoom(ctx, func(m *sharechat.Message) { received <- m.ID })
+		require.NoError(t, err)
+
+		return room, func() []string {
+			ids := []string{}
+			for {
+				select {
+				case id := <-received:
+					ids = append(ids, id)
+				case <-time.After(100 * time.Millisecond):
+					return ids
+				}
+			}
+		}
+	}
+
+	dropped := func() int64 {
+		return expvar.Get("dedup_dropped_messages").(*expvar.Int).Value()
+	}
+
+	controller, queue := newController(0, 0)
+	room, delivered := startRoom(controller)
+	member := sharechat.NewMember("test", room.ID, nil)
+	message := sharechat.NewChatMessage(*member, []byte("hello"))
+	before := dropped()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.Publish(ctx, message))
+	}
+	assert.Equal(t, []string{message.ID}, delivered(), "a message delivered again should be dropped")
+	assert.Equal(t, int64(2), dropped()-before, "dropped duplicates should be counted")
+
+	controller, queue = newController(50*time.Millisecond, 0)
+	room, delivered = startRoom(controller)
+	message = sharechat.NewChatMessage(*sharechat.NewMember("test", room.ID, nil), []byte("hello"))
+	require.NoError(t, queue.Publish(ctx, message))
+	assert.Equal(t, []string{message.ID}, delivered())
+	require.NoError(t, queue.Publish(ctx, message))
+	assert.Equal(t, []string{message.ID}, delivered(), "messages should be forgotten once they leave the window")
+
+	controller, queue = newController(0, 2)
+	room, delivered = startRoom(controller)
+	member = sharechat.NewMember("test", room.ID, nil)
+	messages := []sharechat.Message{}
+	for i := 0; i < 3; i++ {
+		messages = append(messages, sharechat.NewChatMessage(*member, []byte("hello")))
+		require.NoError(t, queue.Publish(ctx, messages[i]))
+	}
+	for _, i := range []int{2, 0} {
+		require.NoError(t, queue.Publish(ctx, messages[i]))
+	}
+	assert.Equal(t, []string{messages[0].ID, messages[1].ID, messages[2].ID, messages[0].ID}, delivered(),
+		"the oldest messages should be forgotten once the room remembers too many")
+}
@@ -0,0 +1,64 @@
+package sharechat
+
+import (
+	"expvar"
+	"time"
+)
+
+const (
+	// defaultDedupWindow is how long the ID of a message delivered to a room is remembered by default
+	defaultDedupWindow = 5 * time.Minute
+	// defaultDedupCapacity is how many message IDs are remembered per room by default
+	defaultDedupCapacity = 1000
+)
+
+// Deduplication metrics, exposed with the rest of expvar at /debug/vars
+var duplicateMessages = expvar.NewInt("dedup_dropped_messages")
+
+// recentMessages remembers the IDs of the messages recently delivered to a room, so that a message
+// delivered again by an at-least-once Queue, the outbox relay or a backfill can be dropped. It holds
+// at most capacity IDs, none of them older than window; the oldest are forgotten first.
+type recentMessages struct {
+	window   time.Duration
+	capacity int
+	seen     map[string]struct{}
+	// order holds the remembered IDs from oldest to newest
+	order []recentMessage
+}
+
+type recentMessage struct {
+	id     string
+	seenAt time.Time
+}
+
+func newRecentMessages(window time.Duration, capacity int) *recentMessages {
+	return &recentMessages{
+		window:   window,
+		capacity: capacity,
+		seen:     make(map[string]struct{}, capacity),
+	}
+}
+
+// Seen reports whether the message was already delivered within the window,
+// remembering it otherwise.
+func (r *recentMessages) Seen(messageID string, now time.Time) bool {
+	for len(r.order) > 0 && now.Sub(r.order[0].seenAt) > r.window {
+		r.forgetOldest()
+	}
+
+	if _, ok := r.seen[messageID]; ok {
+		return true
+	}
+
+	if len(r.order) >= r.capacity {
+		r.forgetOldest()
+	}
+	r.seen[messageID] = struct{}{}
+	r.order = append(r.order, recentMessage{id: messageID, seenAt: now})
+	return false
+}
+
+func (r *recentMessages) forgetOldest() {
+	delete(r.seen, r.order[0].id)
+	r.order = r.order[1:]
+}
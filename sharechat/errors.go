@@ -14,6 +14,9 @@ var ErrRoomNotReady = errors.New("room is not ready")
 // ErrRoomNotShutdown is returned when a room has not yet processed a shutdown request
 var ErrRoomNotShutdown = errors.New("room is not shutdown")
 
+// ErrRoomNotFound is returned when a room does not exist
+var ErrRoomNotFound = errors.New("room not found")
+
 // ErrRoomNotReceiving is returned when a room does not successfully receive messages
 var ErrRoomNotReceiving = errors.New("room not receiving")
 
@@ -27,6 +30,13 @@ var ErrNotListening = errors.New("listen is not ready")
 // ErrNotSubscribed is returned when a Queue is not successfully subscribed to a topic
 var ErrNotSubscribed = errors.New("subscribe is not ready")
 
+// ErrQueueClosed is returned by Subscription.Err when the Queue was closed underneath it
+var ErrQueueClosed = errors.New("queue closed")
+
+// ErrSubscriptionLost is returned by Subscription.Err when messages may have been lost,
+// such as when the Queue reconnected to its broker
+var ErrSubscriptionLost = errors.New("subscription lost")
+
 // ErrNotBroadcasting is returned when a Member is not broadcasting
 var ErrNotBroadcasting = errors.New("broadcast is not ready")
 
@@ -42,3 +52,13 @@ func (e *ErrFailedToPublish) Error() string {
 func (e *ErrFailedToPublish) Unwrap() error {
 	return e.err
 }
+
+// ErrProtocol is returned when a client sends an Envelope that cannot be carried out
+type ErrProtocol struct {
+	Code   ProtocolErrorCode
+	Detail string
+}
+
+func (e *ErrProtocol) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
@@ -0,0 +1,236 @@
+package sharechat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type FrameType string
+
+const (
+	EditFrame    FrameType = "edit"
+	DeleteFrame  FrameType = "delete"
+	ReactFrame   FrameType = "react"
+	UnreactFrame FrameType = "unreact"
+	ReplyFrame   FrameType = "reply"
+)
+
+// maxEmojiLength bounds the size of a reaction; a single emoji with modifiers fits comfortably
+const maxEmojiLength = 32
+
+// ClientFrame is the JSON frame, predating Envelope, that clients send to do something other than chat,
+// e.g. {"type": "edit", "id": "<message-id>", "message": "<new content>"}
+// or {"type": "react", "id": "<message-id>", "emoji": "👍"}.
+// For replies, id is the ID of the message being replied to.
+type ClientFrame struct {
+	Type    FrameType `json:"type"`
+	ID      string    `json:"id"`
+	Message string    `json:"message"`
+	Emoji   string    `json:"emoji"`
+}
+
+// ParseClientFrame decodes a ClientFrame from raw bytes read from a Connection.
+// It returns false if the bytes are not a recognized frame, in which case they should be treated as chat text.
+func ParseClientFrame(raw []byte) (*ClientFrame, bool) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+
+	var frame ClientFrame
+	if err := json.Unmarshal(trimmed, &frame); err != nil {
+		return nil, false
+	}
+
+	switch frame.Type {
+	case EditFrame, ReplyFrame:
+		if frame.ID == "" || frame.Message == "" {
+			return nil, false
+		}
+		return &frame, true
+	case DeleteFrame:
+		if frame.ID == "" {
+			return nil, false
+		}
+		return &frame, true
+	case ReactFrame, UnreactFrame:
+		if frame.ID == "" || frame.Emoji == "" || len(frame.Emoji) > maxEmojiLength {
+			return nil, false
+		}
+		return &frame, true
+	default:
+		return nil, false
+	}
+}
+
+// ProtocolVersion is the version of the Envelope understood by the server
+const ProtocolVersion = 1
+
+// Op is the operation an Envelope asks for
+type Op string
+
+const (
+	ChatOp    Op = "chat"
+	EditOp    Op = "edit"
+	DeleteOp  Op = "delete"
+	ReactOp   Op = "react"
+	UnreactOp Op = "unreact"
+	ReplyOp   Op = "reply"
+	TypingOp  Op = "typing"
+	AckOp     Op = "ack"
+)
+
+// ProtocolErrorCode identifies why an Envelope was rejected
+type ProtocolErrorCode string
+
+const (
+	InvalidEnvelope    ProtocolErrorCode = "invalid_envelope"
+	UnsupportedVersion ProtocolErrorCode = "unsupported_version"
+	UnknownOp          ProtocolErrorCode = "unknown_op"
+	InvalidPayload     ProtocolErrorCode = "invalid_payload"
+)
+
+// Envelope is a versioned JSON frame sent by a client, whose payload depends on its op, e.g.
+// {"v": 1, "op": "chat", "payload": {"message": "hello"}}
+// or {"v": 1, "op": "react", "payload": {"id": "<message-id>", "emoji": "👍"}}.
+// Fields of the payload that an op does not use are ignored.
+type Envelope struct {
+	Version int             `json:"v"`
+	Op      Op              `json:"op"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Payload is the payload of an Envelope. ID is the message an edit, deletion, reaction,
+// reply or acknowledgement refers to.
+type Payload struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Emoji   string `json:"emoji"`
+}
+
+// EncodeEnvelope encodes an Envelope of the current version, for transports that read typed requests
+// rather than raw frames
+func EncodeEnvelope(op Op, payload Payload) ([]byte, error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{Version: ProtocolVersion, Op: op, Payload: rawPayload})
+}
+
+// ParseEnvelope decodes an Envelope from raw bytes read from a Connection. It returns false if the bytes
+// are not an envelope, i.e. a JSON object with an op, in which case they should be treated as a ClientFrame
+// or chat text. An envelope that cannot be decoded is returned with an *ErrProtocol.
+func ParseEnvelope(raw []byte) (*Envelope, bool, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return nil, false, nil
+	}
+	if _, ok := fields["op"]; !ok {
+		return nil, false, nil
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(trimmed, &envelope); err != nil {
+		return nil, true, &ErrProtocol{Code: InvalidEnvelope, Detail: err.Error()}
+	}
+	if envelope.Version != ProtocolVersion {
+		return nil, true, &ErrProtocol{
+			Code:   UnsupportedVersion,
+			Detail: fmt.Sprintf("version %d is not supported, use version %d", envelope.Version, ProtocolVersion),
+		}
+	}
+	return &envelope, true, nil
+}
+
+// Message converts the envelope into the Message it asks the member to send to the Controller
+func (e *Envelope) Message(member Member) (Message, error) {
+	switch e.Op {
+	case ChatOp, EditOp, DeleteOp, ReactOp, UnreactOp, ReplyOp, TypingOp, AckOp:
+	default:
+		return Message{}, &ErrProtocol{Code: UnknownOp, Detail: fmt.Sprintf("unknown op %q", e.Op)}
+	}
+
+	var payload Payload
+	if len(e.Payload) != 0 && !bytes.Equal(e.Payload, []byte("null")) {
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return Message{}, &ErrProtocol{Code: InvalidPayload, Detail: err.Error()}
+		}
+	}
+	invalid := func(detail string) (Message, error) {
+		return Message{}, &ErrProtocol{Code: InvalidPayload, Detail: fmt.Sprintf("%s: %s", e.Op, detail)}
+	}
+	if payload.ID == "" && e.Op != ChatOp && e.Op != TypingOp {
+		return invalid("id is required")
+	}
+
+	switch e.Op {
+	case ChatOp, ReplyOp, EditOp:
+		if payload.Message == "" {
+			return invalid("message is required")
+		}
+		switch e.Op {
+		case ReplyOp:
+			return NewReplyMessage(member, payload.ID, []byte(payload.Message)), nil
+		case EditOp:
+			return NewMessageEditedMessage(member, payload.ID, []byte(payload.Message)), nil
+		}
+		return NewChatMessage(member, []byte(payload.Message)), nil
+	case DeleteOp:
+		return NewMessageDeletedMessage(member, payload.ID), nil
+	case ReactOp, UnreactOp:
+		if payload.Emoji == "" || len(payload.Emoji) > maxEmojiLength {
+			return invalid(fmt.Sprintf("emoji must be 1 to %d bytes", maxEmojiLength))
+		}
+		if e.Op == ReactOp {
+			return NewReactionAddedMessage(member, payload.ID, payload.Emoji), nil
+		}
+		return NewReactionRemovedMessage(member, payload.ID, payload.Emoji), nil
+	case TypingOp:
+		return NewMemberTypingMessage(member), nil
+	default:
+		return NewMessageAckedMessage(member, payload.ID), nil
+	}
+}
+
+// NewMessageFromBytes converts raw bytes read from a member's Connection into the Message to send to the Controller:
+// an Envelope is carried out, or answered with a ProtocolError, while anything else is a ClientFrame or chat text.
+func NewMessageFromBytes(member Member, raw []byte) Message {
+	envelope, ok, err := ParseEnvelope(raw)
+	if ok {
+		var message Message
+		if err == nil {
+			message, err = envelope.Message(member)
+		}
+		var protocolErr *ErrProtocol
+		if errors.As(err, &protocolErr) {
+			return NewProtocolErrorMessage(member, protocolErr)
+		}
+		return message
+	}
+
+	frame, ok := ParseClientFrame(raw)
+	if !ok {
+		return NewChatMessage(member, raw)
+	}
+
+	switch frame.Type {
+	case EditFrame:
+		return NewMessageEditedMessage(member, frame.ID, []byte(frame.Message))
+	case ReplyFrame:
+		return NewReplyMessage(member, frame.ID, []byte(frame.Message))
+	case ReactFrame:
+		return NewReactionAddedMessage(member, frame.ID, frame.Emoji)
+	case UnreactFrame:
+		return NewReactionRemovedMessage(member, frame.ID, frame.Emoji)
+	default:
+		return NewMessageDeletedMessage(member, frame.ID)
+	}
+}
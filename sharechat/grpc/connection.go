@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Connection is a Connection reading & writing the messages of a Member over a Chat stream
+type Connection struct {
+	stream Sharechat_ChatServer
+	// closed ends the stream once the Member stops listening or the client stops sending
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnection(stream Sharechat_ChatServer) *Connection {
+	return &Connection{
+		stream: stream,
+		closed: make(chan struct{}),
+	}
+}
+
+// ReadBytes returns the next request of the stream as the Envelope a websocket client would send
+func (c *Connection) ReadBytes() ([]byte, error) {
+	for {
+		request, err := c.stream.Recv()
+		if err != nil {
+			// the Member leaves the room, so there is nothing left to write
+			_ = c.Close()
+			if errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled {
+				return nil, sharechat.ErrExpectedClose
+			}
+			return nil, err
+		}
+
+		var op sharechat.Op
+		var payload sharechat.Payload
+		switch r := request.Request.(type) {
+		case *ChatRequest_Text:
+			op, payload = sharechat.ChatOp, sharechat.Payload{Message: r.Text}
+		case *ChatRequest_Edit:
+			op, payload = sharechat.EditOp, sharechat.Payload{ID: r.Edit.Id, Message: r.Edit.Message}
+		case *ChatRequest_Delete:
+			op, payload = sharechat.DeleteOp, sharechat.Payload{ID: r.Delete.Id}
+		case *ChatRequest_React:
+			op, payload = sharechat.ReactOp, sharechat.Payload{ID: r.React.Id, Emoji: r.React.Emoji}
+		case *ChatRequest_Unreact:
+			op, payload = sharechat.UnreactOp, sharechat.Payload{ID: r.Unreact.Id, Emoji: r.Unreact.Emoji}
+		case *ChatRequest_Reply:
+			op, payload = sharechat.ReplyOp, sharechat.Payload{ID: r.Reply.Id, Message: r.Reply.Message}
+		case *ChatRequest_Typing:
+			op = sharechat.TypingOp
+		case *ChatRequest_Ack:
+			op, payload = sharechat.AckOp, sharechat.Payload{ID: r.Ack.Id}
+		default:
+			// the member has already joined
+			continue
+		}
+		return sharechat.EncodeEnvelope(op, payload)
+	}
+}
+
+func (c *Connection) WriteMessage(message sharechat.Message) error {
+	return c.stream.Send(toMessage(message))
+}
+
+func (c *Connection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
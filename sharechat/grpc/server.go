@@ -0,0 +1,176 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server serves the Sharechat service, the gRPC counterpart of the HTTP API
+type Server struct {
+	UnimplementedSharechatServer
+	controller *sharechat.Controller
+	Server     *grpc.Server
+}
+
+func NewServer(controller *sharechat.Controller, options ...grpc.ServerOption) *Server {
+	s := Server{controller: controller}
+	s.Server = grpc.NewServer(options...)
+	RegisterSharechatServer(s.Server, &s)
+	return &s
+}
+
+func (s *Server) CreateRoom(ctx context.Context, request *CreateRoomRequest) (*Room, error) {
+	room, err := s.controller.CreateRoom(context.Background())
+	if err != nil {
+		log.Printf("failed to create room: %v", err)
+		return nil, status.Error(codes.Internal, "failed to create room")
+	}
+
+	return &Room{Id: room.ID, Name: room.Name}, nil
+}
+
+func (s *Server) GetRoom(ctx context.Context, request *GetRoomRequest) (*GetRoomResponse, error) {
+	room, err := s.controller.GetRoom(ctx, request.RoomId)
+	if err != nil {
+		if errors.Is(err, sharechat.ErrRoomNotFound) {
+			return nil, status.Error(codes.NotFound, "room not found")
+		}
+		log.Printf("failed to get room: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get room")
+	}
+
+	response := &GetRoomResponse{Room: &Room{Id: room.RoomID, Name: room.RoomName}}
+	for _, member := range room.Members {
+		response.Members = append(response.Members, &Member{Id: member.ID, Name: member.Name, RoomId: member.RoomID})
+	}
+	return response, nil
+}
+
+func (s *Server) GetMessages(ctx context.Context, request *GetMessagesRequest) (*GetMessagesResponse, error) {
+	options := sharechat.GetMessageOptions{
+		Limit:     int(request.Limit),
+		RoomID:    request.RoomId,
+		ThreadID:  request.ThreadId,
+		AfterSeq:  request.AfterSeq,
+		BeforeSeq: request.BeforeSeq,
+	}
+	if err := options.After.DecodeFromString(request.After); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := options.Before.DecodeFromString(request.Before); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	messages, err := s.controller.GetMessages(ctx, options)
+	if err != nil {
+		if errors.Is(err, sharechat.ErrInvalidOptions) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		log.Printf("failed to get messages for room %s: %v", request.RoomId, err)
+		return nil, status.Error(codes.Internal, "failed to get messages")
+	}
+
+	response := &GetMessagesResponse{}
+	for _, message := range messages {
+		response.Messages = append(response.Messages, toMessage(message))
+	}
+	if len(messages) > 0 {
+		next := sharechat.MessageCursor{
+			Sent: messages[len(messages)-1].Sent,
+			ID:   messages[len(messages)-1].ID,
+		}
+		response.Next = next.Encode()
+	}
+	return response, nil
+}
+
+// Chat serves a room to the Member of the stream until either the client or the room closes it
+func (s *Server) Chat(stream Sharechat_ChatServer) error {
+	request, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	join := request.GetJoin()
+	if join == nil {
+		return status.Error(codes.InvalidArgument, "the first request must join a room")
+	}
+
+	conn := newConnection(stream)
+	if err := s.controller.ServeRoom(context.Background(), join.RoomId, conn); err != nil {
+		var publishErr *sharechat.ErrFailedToPublish
+		switch {
+		case errors.As(err, &publishErr):
+			log.Printf("room %s is serving, but member failed to publish: %v", join.RoomId, publishErr)
+		case errors.Is(err, sharechat.ErrRoomNotFound):
+			return status.Error(codes.NotFound, "room not found")
+		default:
+			log.Printf("failed to serve room %s: %v", join.RoomId, err)
+			return status.Error(codes.Internal, "failed to serve room")
+		}
+	}
+
+	select {
+	case <-conn.closed:
+	case <-stream.Context().Done():
+	}
+	return nil
+}
+
+var messageTypes = map[sharechat.MessageType]MessageType{
+	sharechat.Chat:            MessageType_MESSAGE_TYPE_CHAT,
+	sharechat.MemberJoined:    MessageType_MESSAGE_TYPE_JOINED,
+	sharechat.MemberLeft:      MessageType_MESSAGE_TYPE_LEFT,
+	sharechat.SendFailed:      MessageType_MESSAGE_TYPE_FAILED,
+	sharechat.RoomExpired:     MessageType_MESSAGE_TYPE_EXPIRED,
+	sharechat.MessageEdited:   MessageType_MESSAGE_TYPE_EDITED,
+	sharechat.MessageDeleted:  MessageType_MESSAGE_TYPE_DELETED,
+	sharechat.ReactionAdded:   MessageType_MESSAGE_TYPE_REACTED,
+	sharechat.ReactionRemoved: MessageType_MESSAGE_TYPE_UNREACTED,
+	sharechat.MemberTyping:    MessageType_MESSAGE_TYPE_TYPING,
+	sharechat.MessageAcked:    MessageType_MESSAGE_TYPE_ACKED,
+	sharechat.ProtocolError:   MessageType_MESSAGE_TYPE_ERROR,
+}
+
+func toMessage(message sharechat.Message) *Message {
+	result := &Message{
+		Id:         message.ID,
+		RoomId:     message.RoomID,
+		MemberId:   message.MemberID,
+		MemberName: message.MemberName,
+		Type:       messageTypes[message.Type],
+		Message:    message.Message,
+		Sent:       toTimestamp(&message.Sent),
+		Seq:        message.Seq,
+		EditedAt:   toTimestamp(message.EditedAt),
+		TargetId:   message.TargetID,
+		Emoji:      message.Emoji,
+		ParentId:   message.ParentID,
+		ReplyCount: int64(message.ReplyCount),
+		LastReply:  toTimestamp(message.LastReply),
+		Cluster:    message.Cluster,
+		Code:       string(message.Code),
+	}
+
+	if len(message.Reactions) != 0 {
+		result.Reactions = make(map[string]int64, len(message.Reactions))
+		for emoji, count := range message.Reactions {
+			result.Reactions[emoji] = int64(count)
+		}
+	}
+	return result
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: sharechat/grpc/sharechat.proto
+
+package grpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Sharechat_CreateRoom_FullMethodName  = "/sharechat.v1.Sharechat/CreateRoom"
+	Sharechat_GetRoom_FullMethodName     = "/sharechat.v1.Sharechat/GetRoom"
+	Sharechat_GetMessages_FullMethodName = "/sharechat.v1.Sharechat/GetMessages"
+	Sharechat_Chat_FullMethodName        = "/sharechat.v1.Sharechat/Chat"
+)
+
+// SharechatClient is the client API for Sharechat service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SharechatClient interface {
+	CreateRoom(ctx context.Context, in *CreateRoomRequest, opts ...grpc.CallOption) (*Room, error)
+	GetRoom(ctx context.Context, in *GetRoomRequest, opts ...grpc.CallOption) (*GetRoomResponse, error)
+	GetMessages(ctx context.Context, in *GetMessagesRequest, opts ...grpc.CallOption) (*GetMessagesResponse, error)
+	// Chat joins a room as a new member. The first request must be a join; the member leaves the room
+	// once the client closes the stream. The first message streamed back is the member joining.
+	Chat(ctx context.Context, opts ...grpc.CallOption) (Sharechat_ChatClient, error)
+}
+
+type sharechatClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSharechatClient(cc grpc.ClientConnInterface) SharechatClient {
+	return &sharechatClient{cc}
+}
+
+func (c *sharechatClient) CreateRoom(ctx context.Context, in *CreateRoomRequest, opts ...grpc.CallOption) (*Room, error) {
+	out := new(Room)
+	err := c.cc.Invoke(ctx, Sharechat_CreateRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sharechatClient) GetRoom(ctx context.Context, in *GetRoomRequest, opts ...grpc.CallOption) (*GetRoomResponse, error) {
+	out := new(GetRoomResponse)
+	err := c.cc.Invoke(ctx, Sharechat_GetRoom_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sharechatClient) GetMessages(ctx context.Context, in *GetMessagesRequest, opts ...grpc.CallOption) (*GetMessagesResponse, error) {
+	out := new(GetMessagesResponse)
+	err := c.cc.Invoke(ctx, Sharechat_GetMessages_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sharechatClient) Chat(ctx context.Context, opts ...grpc.CallOption) (Sharechat_ChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Sharechat_ServiceDesc.Streams[0], Sharechat_Chat_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sharechatChatClient{stream}
+	return x, nil
+}
+
+type Sharechat_ChatClient interface {
+	Send(*ChatRequest) error
+	Recv() (*Message, error)
+	grpc.ClientStream
+}
+
+type sharechatChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *sharechatChatClient) Send(m *ChatRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sharechatChatClient) Recv() (*Message, error) {
+	m := new(Message)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SharechatServer is the server API for Sharechat service.
+// All implementations must embed UnimplementedSharechatServer
+// for forward compatibility
+type SharechatServer interface {
+	CreateRoom(context.Context, *CreateRoomRequest) (*Room, error)
+	GetRoom(context.Context, *GetRoomRequest) (*GetRoomResponse, error)
+	GetMessages(context.Context, *GetMessagesRequest) (*GetMessagesResponse, error)
+	// Chat joins a room as a new member. The first request must be a join; the member leaves the room
+	// once the client closes the stream. The first message streamed back is the member joining.
+	Chat(Sharechat_ChatServer) error
+	mustEmbedUnimplementedSharechatServer()
+}
+
+// UnimplementedSharechatServer must be embedded to have forward compatible implementations.
+type UnimplementedSharechatServer struct {
+}
+
+func (UnimplementedSharechatServer) CreateRoom(context.Context, *CreateRoomRequest) (*Room, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRoom not implemented")
+}
+func (UnimplementedSharechatServer) GetRoom(context.Context, *GetRoomRequest) (*GetRoomResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoom not implemented")
+}
+func (UnimplementedSharechatServer) GetMessages(context.Context, *GetMessagesRequest) (*GetMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMessages not implemented")
+}
+func (UnimplementedSharechatServer) Chat(Sharechat_ChatServer) error {
+	return status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedSharechatServer) mustEmbedUnimplementedSharechatServer() {}
+
+// UnsafeSharechatServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SharechatServer will
+// result in compilation errors.
+type UnsafeSharechatServer interface {
+	mustEmbedUnimplementedSharechatServer()
+}
+
+func RegisterSharechatServer(s grpc.ServiceRegistrar, srv SharechatServer) {
+	s.RegisterService(&Sharechat_ServiceDesc, srv)
+}
+
+func _Sharechat_CreateRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SharechatServer).CreateRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sharechat_CreateRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SharechatServer).CreateRoom(ctx, req.(*CreateRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sharechat_GetRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SharechatServer).GetRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sharechat_GetRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SharechatServer).GetRoom(ctx, req.(*GetRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sharechat_GetMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SharechatServer).GetMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sharechat_GetMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SharechatServer).GetMessages(ctx, req.(*GetMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sharechat_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SharechatServer).Chat(&sharechatChatServer{stream})
+}
+
+type Sharechat_ChatServer interface {
+	Send(*Message) error
+	Recv() (*ChatRequest, error)
+	grpc.ServerStream
+}
+
+type sharechatChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *sharechatChatServer) Send(m *Message) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sharechatChatServer) Recv() (*ChatRequest, error) {
+	m := new(ChatRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Sharechat_ServiceDesc is the grpc.ServiceDesc for Sharechat service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sharechat_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sharechat.v1.Sharechat",
+	HandlerType: (*SharechatServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateRoom",
+			Handler:    _Sharechat_CreateRoom_Handler,
+		},
+		{
+			MethodName: "GetRoom",
+			Handler:    _Sharechat_GetRoom_Handler,
+		},
+		{
+			MethodName: "GetMessages",
+			Handler:    _Sharechat_GetMessages_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Chat",
+			Handler:       _Sharechat_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "sharechat/grpc/sharechat.proto",
+}
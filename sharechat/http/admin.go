@@ -0,0 +1,26 @@
+package http
+
+import (
+	"expvar"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// newAdminServer returns the server for operators, which is kept apart from the public API since it
+// deletes rooms, overrides their retention and exposes the expvar metrics at /debug/vars, which include
+// the command line & memory stats. It has no address until one is set, and should only be served on an
+// internal address.
+func (s *Server) newAdminServer() *http.Server {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/room/{room}", s.DeleteRoom).Methods(http.MethodDelete)
+	router.HandleFunc("/api/room/{room}/retention", s.SetRoomRetention).Methods(http.MethodPut)
+	router.Handle("/debug/vars", expvar.Handler()).Methods(http.MethodGet)
+
+	return &http.Server{
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+}
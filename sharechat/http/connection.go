@@ -34,3 +34,7 @@ func (w *Connection) ReadBytes() ([]byte, error) {
 func (w *Connection) WriteMessage(v sharechat.Message) error {
 	return w.conn.WriteJSON(v)
 }
+
+func (w *Connection) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,373 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	// SessionHeader identifies the session a message sent over HTTP belongs to
+	SessionHeader = "X-Sharechat-Session"
+	// eventBuffer bounds the messages waiting to be written to an event stream
+	eventBuffer = 64
+	// keepAliveInterval is how often an idle event stream is sent a comment, so proxies keep it open
+	keepAliveInterval = 15 * time.Second
+	// replayPageSize & replayLimit bound the messages replayed to a resumed event stream
+	replayPageSize = 100
+	replayLimit    = 1000
+	// maxMessageBytes bounds the body of a message sent over HTTP
+	maxMessageBytes = 64 << 10
+	// sendTimeout bounds waiting for a Member to read a message sent over HTTP
+	sendTimeout = 5 * time.Second
+)
+
+// ErrSessionClosed is returned when sending to or writing to a session that has ended
+var ErrSessionClosed = errors.New("session closed")
+
+// EventStream is a Connection for clients that cannot open a websocket. Messages written to it are
+// streamed as Server-Sent Events, while the messages it reads are the bodies of separate requests
+// sent to its session.
+type EventStream struct {
+	session string
+	events  chan sharechat.Message
+	sent    chan []byte
+	// closed is closed by Close, once the Member stops listening
+	closed    chan struct{}
+	closeOnce sync.Once
+	// disconnected is closed once the client stops reading the stream
+	disconnected   chan struct{}
+	disconnectOnce sync.Once
+}
+
+func newEventStream() *EventStream {
+	return &EventStream{
+		session:      uuid.New().String(),
+		events:       make(chan sharechat.Message, eventBuffer),
+		sent:         make(chan []byte),
+		closed:       make(chan struct{}),
+		disconnected: make(chan struct{}),
+	}
+}
+
+func (e *EventStream) WriteMessage(message sharechat.Message) error {
+	select {
+	case e.events <- message:
+		return nil
+	case <-e.closed:
+		return ErrSessionClosed
+	case <-e.disconnected:
+		return ErrSessionClosed
+	}
+}
+
+// ReadBytes returns the next message sent to the session, or ErrExpectedClose once the client disconnects
+func (e *EventStream) ReadBytes() ([]byte, error) {
+	select {
+	case bytes := <-e.sent:
+		return bytes, nil
+	case <-e.closed:
+		return nil, sharechat.ErrExpectedClose
+	case <-e.disconnected:
+		return nil, sharechat.ErrExpectedClose
+	}
+}
+
+func (e *EventStream) Close() error {
+	e.closeOnce.Do(func() {
+		close(e.closed)
+	})
+	return nil
+}
+
+// Send hands a message sent to the session to its Member, waiting until the Member reads it
+func (e *EventStream) Send(ctx context.Context, message []byte) error {
+	select {
+	case e.sent <- message:
+		return nil
+	case <-e.closed:
+		return ErrSessionClosed
+	case <-e.disconnected:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *EventStream) disconnect() {
+	e.disconnectOnce.Do(func() {
+		close(e.disconnected)
+	})
+}
+
+// Session identifies the Member of a client joined over HTTP: it is the first event of an event stream,
+// & the response to joining for long polling. Its ID is sent with messages for the Member, either as
+// the X-Sharechat-Session header or the session query parameter.
+type Session struct {
+	ID         string `json:"session"`
+	MemberID   string `json:"memberId"`
+	MemberName string `json:"memberName"`
+}
+
+// StreamRoomEvents joins the room as a new Member & streams the room's messages as Server-Sent Events.
+// A client reconnecting with a Last-Event-ID header is sent the messages it missed first, though as a new Member.
+func (s *Server) StreamRoomEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Printf("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	after := sharechat.MessageCursor{}
+	if err := after.DecodeFromString(r.Header.Get("Last-Event-ID")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the stream outlives the server's write timeout
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("cannot stream events: %v", err)
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	stream := newEventStream()
+	defer stream.disconnect()
+
+	member, ok := s.joinRoom(w, roomID, stream)
+	if !ok {
+		return
+	}
+
+	s.addSession(stream.session, roomID, stream)
+	defer s.removeSession(stream.session)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	session := Session{ID: stream.session, MemberID: member.ID, MemberName: member.Name}
+	if err := writeEvent(w, "session", "", session); err != nil {
+		return
+	}
+
+	// messages published while replaying are written to the stream as well, so they are skipped once
+	replayed := map[string]struct{}{}
+	if !after.IsEmpty() {
+		messages, err := s.replay(r.Context(), roomID, after)
+		if err != nil {
+			log.Printf("failed to replay messages of room %s: %v", roomID, err)
+		}
+		for _, message := range messages {
+			if err := writeMessageEvent(w, message); err != nil {
+				return
+			}
+			replayed[message.ID] = struct{}{}
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message := <-stream.events:
+			if _, ok := replayed[message.ID]; ok {
+				delete(replayed, message.ID)
+				continue
+			}
+			if err := writeMessageEvent(w, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-stream.closed:
+			return
+		case <-r.Context().Done():
+			return
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// joinRoom joins the room as a new Member of the connection, writing an error response if it fails
+func (s *Server) joinRoom(w http.ResponseWriter, roomID string, conn sharechat.Connection) (*sharechat.Member, bool) {
+	member, err := s.controller.JoinRoom(context.Background(), roomID, conn)
+	if err != nil {
+		var publishErr *sharechat.ErrFailedToPublish
+		switch {
+		case errors.As(err, &publishErr):
+			log.Printf("room %s is serving, but member failed to publish: %v", roomID, publishErr)
+		case errors.Is(err, sharechat.ErrRoomNotFound):
+			http.Error(w, "room not found", http.StatusNotFound)
+			return nil, false
+		default:
+			log.Printf("failed to serve room %s: %v", roomID, err)
+			http.Error(w, "failed to serve room", http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+	return member, true
+}
+
+// replay returns the messages of the room sent after the cursor, replies included, oldest first
+func (s *Server) replay(ctx context.Context, roomID string, after sharechat.MessageCursor) ([]sharechat.Message, error) {
+	messages := []sharechat.Message{}
+	for len(messages) < replayLimit {
+		page, err := s.controller.GetMessages(ctx, sharechat.GetMessageOptions{
+			RoomID:         roomID,
+			Limit:          replayPageSize,
+			After:          after,
+			IncludeReplies: true,
+		})
+		if err != nil {
+			return messages, err
+		}
+
+		messages = append(messages, page...)
+		if len(page) < replayPageSize {
+			break
+		}
+		last := page[len(page)-1]
+		after = sharechat.MessageCursor{Sent: last.Sent, ID: last.ID}
+	}
+	return messages, nil
+}
+
+// writeMessageEvent writes a message as an event whose ID is the message's cursor, so that a client
+// reconnecting resumes after the last message it received
+func writeMessageEvent(w io.Writer, message sharechat.Message) error {
+	id := ""
+	if !message.Sent.IsZero() {
+		cursor := sharechat.MessageCursor{Sent: message.Sent, ID: message.ID}
+		id = cursor.Encode()
+	}
+	return writeEvent(w, "", id, message)
+}
+
+func writeEvent(w io.Writer, event, id string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// SendRoomMessage sends the body of the request to the room as the Member of a session,
+// the way a websocket client sends a frame
+func (s *Server) SendRoomMessage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Printf("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	conn, ok := s.getSession(roomID, r)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(body) == 0 {
+		http.Error(w, "message is empty", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), sendTimeout)
+	defer cancel()
+
+	if err := conn.Send(ctx, body); err != nil {
+		if errors.Is(err, ErrSessionClosed) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("failed to send message to room %s: %v", roomID, err)
+		http.Error(w, "failed to send message", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sessionConnection is a Connection whose messages are sent by separate requests
+type sessionConnection interface {
+	sharechat.Connection
+	Send(ctx context.Context, message []byte) error
+}
+
+// session is the Connection of a Member joined over HTTP without a websocket
+type session struct {
+	roomID string
+	conn   sessionConnection
+}
+
+func (s *Server) addSession(id, roomID string, conn sessionConnection) {
+	s.mu.Lock()
+	s.sessions[id] = session{roomID: roomID, conn: conn}
+	s.mu.Unlock()
+}
+
+// getSession returns the Connection of the session of a request, passed either as the
+// X-Sharechat-Session header or the session query parameter, if it belongs to the room
+func (s *Server) getSession(roomID string, r *http.Request) (sessionConnection, bool) {
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		id = r.URL.Query().Get("session")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || session.roomID != roomID {
+		return nil, false
+	}
+	return session.conn, true
+}
+
+func (s *Server) removeSession(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
@@ -0,0 +1,266 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	// DefaultPollSessionTTL is how long a long polling session lives without being polled
+	DefaultPollSessionTTL = time.Minute
+	// defaultPollTimeout & maxPollTimeout bound how long a poll waits for messages
+	defaultPollTimeout = 25 * time.Second
+	maxPollTimeout     = 55 * time.Second
+	// pollBuffer bounds the messages buffered for a long polling session; once full, the oldest are dropped
+	pollBuffer = 1000
+)
+
+// PollSession is a buffered Connection for clients that can neither open a websocket nor read an event
+// stream. Messages written to it are buffered until the client polls them, while the messages it reads
+// are the bodies of separate requests sent to its session. The session expires once it has not been
+// polled for its TTL, so its Member leaves the room.
+type PollSession struct {
+	session string
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	buffer []sharechat.Message
+	// polls counts the polls in flight; the session does not expire while it is polled
+	polls  int
+	expiry *time.Timer
+	// ready is signalled when messages are buffered
+	ready chan struct{}
+
+	sent chan []byte
+	// closed is closed by Close, once the Member stops listening
+	closed    chan struct{}
+	closeOnce sync.Once
+	// expired is closed once the session has not been polled for its TTL, whether or not it is closed
+	expired    chan struct{}
+	expireOnce sync.Once
+}
+
+func newPollSession(ttl time.Duration) *PollSession {
+	p := &PollSession{
+		session: uuid.New().String(),
+		ttl:     ttl,
+		ready:   make(chan struct{}, 1),
+		sent:    make(chan []byte),
+		closed:  make(chan struct{}),
+		expired: make(chan struct{}),
+	}
+	p.expiry = time.AfterFunc(ttl, p.expire)
+	return p
+}
+
+// WriteMessage buffers a message until the session is polled
+func (p *PollSession) WriteMessage(message sharechat.Message) error {
+	if p.ended() {
+		return ErrSessionClosed
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buffer) >= pollBuffer {
+		p.buffer = p.buffer[1:]
+	}
+	p.buffer = append(p.buffer, message)
+
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// ReadBytes returns the next message sent to the session, or ErrExpectedClose once the session expires
+func (p *PollSession) ReadBytes() ([]byte, error) {
+	select {
+	case bytes := <-p.sent:
+		return bytes, nil
+	case <-p.closed:
+		return nil, sharechat.ErrExpectedClose
+	case <-p.expired:
+		return nil, sharechat.ErrExpectedClose
+	}
+}
+
+func (p *PollSession) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+	return nil
+}
+
+// Send hands a message sent to the session to its Member, waiting until the Member reads it
+func (p *PollSession) Send(ctx context.Context, message []byte) error {
+	select {
+	case p.sent <- message:
+		return nil
+	case <-p.closed:
+		return ErrSessionClosed
+	case <-p.expired:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Poll returns the buffered messages, waiting up to timeout for messages to be written if there are none.
+// ErrSessionClosed is returned once every message written before the session ended has been polled.
+func (p *PollSession) Poll(ctx context.Context, timeout time.Duration) ([]sharechat.Message, error) {
+	p.mu.Lock()
+	p.polls++
+	p.expiry.Stop()
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.polls--
+		if p.polls == 0 {
+			p.expiry.Reset(p.ttl)
+		}
+		p.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		if messages := p.take(); len(messages) != 0 {
+			return messages, nil
+		}
+		if p.ended() {
+			return nil, ErrSessionClosed
+		}
+
+		select {
+		case <-p.ready:
+		case <-p.closed:
+		case <-p.expired:
+		case <-timer.C:
+			return []sharechat.Message{}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// take empties the buffer, returning the messages it held
+func (p *PollSession) take() []sharechat.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	messages := p.buffer
+	p.buffer = nil
+	return messages
+}
+
+func (p *PollSession) ended() bool {
+	select {
+	case <-p.closed:
+		return true
+	case <-p.expired:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *PollSession) expire() {
+	p.expireOnce.Do(func() {
+		close(p.expired)
+	})
+}
+
+// JoinRoomSession joins the room as a new Member whose messages are buffered until its session is polled
+func (s *Server) JoinRoomSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Printf("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	session := newPollSession(s.pollSessionTTL)
+	member, ok := s.joinRoom(w, roomID, session)
+	if !ok {
+		session.expire()
+		return
+	}
+
+	// a closed session is kept until it expires, so the client can poll the messages written before it closed
+	s.addSession(session.session, roomID, session)
+	go func() {
+		<-session.expired
+		s.removeSession(session.session)
+	}()
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Session{ID: session.session, MemberID: member.ID, MemberName: member.Name})
+}
+
+// PollResponse holds the messages written to a long polling session since it was last polled
+type PollResponse struct {
+	Messages []sharechat.Message `json:"messages"`
+}
+
+// PollRoom returns the messages of the room written to a long polling session, waiting for the timeout
+// query parameter, in seconds, for messages to be written if there are none
+func (s *Server) PollRoom(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Printf("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	conn, ok := s.getSession(roomID, r)
+	session, isPoll := conn.(*PollSession)
+	if !ok || !isPoll {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	timeout := defaultPollTimeout
+	if rawTimeout := r.URL.Query().Get("timeout"); rawTimeout != "" {
+		seconds, err := strconv.Atoi(rawTimeout)
+		if err != nil || seconds < 0 {
+			http.Error(w, "timeout must be a number of seconds", http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+		if timeout > maxPollTimeout {
+			timeout = maxPollTimeout
+		}
+	}
+
+	// the poll may outlive the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + sendTimeout)); err != nil {
+		log.Printf("cannot extend poll deadline: %v", err)
+	}
+
+	messages, err := session.Poll(r.Context(), timeout)
+	switch {
+	case errors.Is(err, ErrSessionClosed):
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	case err != nil:
+		// the client went away
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(PollResponse{Messages: messages})
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -31,6 +32,20 @@ type Server struct {
 	upgrader   *websocket.Upgrader
 	controller *sharechat.Controller
 	Server     *http.Server
+	// Admin serves the endpoints for operators; see newAdminServer
+	Admin *http.Server
+
+	mu sync.Mutex
+	// sessions holds the Connections of Members joined over HTTP by session
+	sessions       map[string]session
+	pollSessionTTL time.Duration
+}
+
+// Health is the body of the healthcheck of a healthy Controller. A Controller is degraded while it
+// recovers lost subscriptions; it keeps serving, but the recovering rooms may be missing messages.
+type Health struct {
+	Status          string   `json:"status"`
+	RecoveringRooms []string `json:"recoveringRooms,omitempty"`
 }
 
 func (s *Server) Health(w http.ResponseWriter, r *http.Request) {
@@ -39,7 +54,14 @@ func (s *Server) Health(w http.ResponseWriter, r *http.Request) {
 		log.Printf("healthcheck failed: %v", err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+
+	health := Health{Status: "ok"}
+	if rooms := s.controller.RecoveringRooms(); len(rooms) != 0 {
+		health = Health{Status: "degraded", RecoveringRooms: rooms}
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(health)
 }
 
 func (s *Server) CreateRoom(w http.ResponseWriter, r *http.Request) {
@@ -74,6 +96,114 @@ func (s *Server) GetRoom(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+func (s *Server) DeleteRoom(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Print("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.controller.DeleteRoom(ctx, roomID); err != nil {
+		var publishErr *sharechat.ErrFailedToPublish
+		switch {
+		case errors.Is(err, sharechat.ErrRoomNotFound):
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		case errors.As(err, &publishErr):
+			// the room is already deleted, so there is nothing for the client to retry
+			log.Printf("room %s deleted, but failed to notify members: %v", roomID, publishErr)
+		default:
+			log.Printf("failed to delete room %s: %v", roomID, err)
+			http.Error(w, "failed to delete room", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RoomRetention is a room's retention policy override; limits left empty fall back to the global policy
+type RoomRetention struct {
+	// MaxAge is a duration such as "720h"
+	MaxAge      string `json:"maxAge,omitempty"`
+	MaxMessages int    `json:"maxMessages,omitempty"`
+}
+
+func (s *Server) GetRoomRetention(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Print("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := s.controller.GetRoomRetention(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, sharechat.ErrRoomNotFound) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("failed to get retention of room %s: %v", roomID, err)
+		http.Error(w, "failed to get room retention", http.StatusInternalServerError)
+		return
+	}
+
+	response := RoomRetention{MaxMessages: policy.MaxMessages}
+	if policy.MaxAge != 0 {
+		response.MaxAge = policy.MaxAge.String()
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) SetRoomRetention(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Print("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	var request RoomRetention
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	policy := sharechat.RetentionPolicy{MaxMessages: request.MaxMessages}
+	if request.MaxAge != "" {
+		maxAge, err := time.ParseDuration(request.MaxAge)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		policy.MaxAge = maxAge
+	}
+
+	if err := s.controller.SetRoomRetention(r.Context(), roomID, policy); err != nil {
+		switch {
+		case errors.Is(err, sharechat.ErrInvalidOptions):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, sharechat.ErrRoomNotFound):
+			http.Error(w, "room not found", http.StatusNotFound)
+		default:
+			log.Printf("failed to set retention of room %s: %v", roomID, err)
+			http.Error(w, "failed to set room retention", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 type GetRoomMessagesResponse struct {
 	Messages   []sharechat.Message `json:"messages"`
 	NumResults int                 `json:"numResults"`
@@ -94,6 +224,11 @@ func (s *Server) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
 		RoomID: roomID,
 	}
 
+	// the thread route shares this handler; it scopes the results to a message's replies
+	if threadID, ok := vars["message"]; ok {
+		options.ThreadID = threadID
+	}
+
 	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
 		limit, err := strconv.Atoi(rawLimit)
 		if err != nil {
@@ -123,6 +258,24 @@ func (s *Server) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
 		options.Before = cursor
 	}
 
+	if rawSeq := r.URL.Query().Get("afterSeq"); rawSeq != "" {
+		seq, err := strconv.ParseInt(rawSeq, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.AfterSeq = seq
+	}
+
+	if rawSeq := r.URL.Query().Get("beforeSeq"); rawSeq != "" {
+		seq, err := strconv.ParseInt(rawSeq, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.BeforeSeq = seq
+	}
+
 	messages, err := s.controller.GetMessages(r.Context(), options)
 	if err != nil {
 		if errors.Is(err, sharechat.ErrInvalidOptions) {
@@ -153,6 +306,78 @@ func (s *Server) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+type SearchRoomMessagesResponse struct {
+	Results    []sharechat.SearchResult `json:"results"`
+	NumResults int                      `json:"numResults"`
+	Next       string                   `json:"next"`
+}
+
+func (s *Server) SearchRoomMessages(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, ok := vars["room"]
+	if !ok {
+		log.Printf("room path parameter not provided")
+		http.Error(w, "path parameter not provided", http.StatusBadRequest)
+		return
+	}
+
+	options := sharechat.SearchMessageOptions{
+		Limit:  0,
+		RoomID: roomID,
+		Query:  r.URL.Query().Get("q"),
+	}
+
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.Limit = limit
+	}
+
+	if rawCursor := r.URL.Query().Get("after"); rawCursor != "" {
+		cursor := sharechat.SearchCursor{}
+		if err := cursor.DecodeFromString(rawCursor); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		options.After = cursor
+	}
+
+	results, err := s.controller.SearchMessages(r.Context(), options)
+	if err != nil {
+		if errors.Is(err, sharechat.ErrInvalidOptions) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("failed to search messages for room %s: %v", roomID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	nextCursor := sharechat.SearchCursor{}
+	if len(results) > 0 {
+		last := results[len(results)-1]
+		nextCursor = sharechat.SearchCursor{
+			ID:   last.ID,
+			Sent: last.Sent,
+			Rank: last.Rank,
+		}
+	}
+
+	response := SearchRoomMessagesResponse{
+		Results:    results,
+		NumResults: len(results),
+		Next:       nextCursor.Encode(),
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
 func (s *Server) ServeRoom(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomID, ok := vars["room"]
@@ -179,6 +404,12 @@ func (s *Server) ServeRoom(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// WithPollSessionTTL sets how long a long polling session lives without being polled
+func (s *Server) WithPollSessionTTL(ttl time.Duration) *Server {
+	s.pollSessionTTL = ttl
+	return s
+}
+
 func NewServer(controller *sharechat.Controller, upgrader websocket.Upgrader, corsOptons cors.Options) *Server {
 	router := mux.NewRouter()
 	c := cors.New(corsOptons)
@@ -186,11 +417,21 @@ func NewServer(controller *sharechat.Controller, upgrader websocket.Upgrader, co
 	s := Server{
 		upgrader:   &upgrader,
 		controller: controller,
+		sessions:   make(map[string]session),
+
+		pollSessionTTL: DefaultPollSessionTTL,
 	}
 
 	router.HandleFunc("/api/room", s.CreateRoom).Methods(http.MethodPost, http.MethodOptions)
 	router.HandleFunc("/api/room/{room}/messages", s.GetRoomMessages).Methods(http.MethodGet, http.MethodOptions)
+	router.HandleFunc("/api/room/{room}/messages", s.SendRoomMessage).Methods(http.MethodPost)
+	router.HandleFunc("/api/room/{room}/events", s.StreamRoomEvents).Methods(http.MethodGet, http.MethodOptions)
+	router.HandleFunc("/api/room/{room}/sessions", s.JoinRoomSession).Methods(http.MethodPost)
+	router.HandleFunc("/api/room/{room}/poll", s.PollRoom).Methods(http.MethodGet, http.MethodOptions)
+	router.HandleFunc("/api/room/{room}/search", s.SearchRoomMessages).Methods(http.MethodGet, http.MethodOptions)
+	router.HandleFunc("/api/room/{room}/messages/{message}/thread", s.GetRoomMessages).Methods(http.MethodGet, http.MethodOptions)
 	router.HandleFunc("/api/room/{room}", s.GetRoom).Methods(http.MethodGet, http.MethodOptions)
+	router.HandleFunc("/api/room/{room}/retention", s.GetRoomRetention).Methods(http.MethodGet, http.MethodOptions)
 	router.HandleFunc("/api/serve/{room}", s.ServeRoom).Methods(http.MethodGet, http.MethodOptions)
 	router.HandleFunc("/api/healthz", s.Health).Methods(http.MethodGet, http.MethodOptions)
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -206,6 +447,7 @@ func NewServer(controller *sharechat.Controller, upgrader websocket.Upgrader, co
 	}
 
 	s.Server = &server
+	s.Admin = s.newAdminServer()
 
 	return &s
 }
@@ -0,0 +1,107 @@
+package irc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+// ErrNotOnChannel is returned when sending to a channel the client has parted or that has closed
+var ErrNotOnChannel = errors.New("not on channel")
+
+// Channel is the Connection of the Member a client joins a room as. Its messages are written to
+// the client as IRC lines, and it reads the messages the client sends to the channel.
+type Channel struct {
+	name   string
+	roomID string
+	client *client
+	// memberID is set once the Member has joined, before joined is closed
+	memberID string
+	// joined is closed once the client has been told it joined the channel,
+	// so that the room's messages follow the JOIN
+	joined chan struct{}
+
+	sent chan []byte
+	// parted is closed once the client leaves the channel, so the Member leaves the room
+	parted   chan struct{}
+	partOnce sync.Once
+	// closed is closed by Close, once the Member stops listening
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newChannel(client *client, roomID string) *Channel {
+	return &Channel{
+		name:   "#" + roomID,
+		roomID: roomID,
+		client: client,
+		joined: make(chan struct{}),
+		sent:   make(chan []byte),
+		parted: make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+}
+
+// ReadBytes returns the next message the client sends to the channel,
+// or ErrExpectedClose once the client leaves it
+func (c *Channel) ReadBytes() ([]byte, error) {
+	select {
+	case bytes := <-c.sent:
+		return bytes, nil
+	case <-c.parted:
+		return nil, sharechat.ErrExpectedClose
+	case <-c.closed:
+		return nil, sharechat.ErrExpectedClose
+	}
+}
+
+// WriteMessage writes a message of the room to the client, unless it has left the channel
+func (c *Channel) WriteMessage(message sharechat.Message) error {
+	select {
+	case <-c.joined:
+	case <-c.parted:
+		return nil
+	}
+	if c.hasParted() {
+		return nil
+	}
+	c.client.relay(c, message)
+	return nil
+}
+
+func (c *Channel) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.client.removeChannel(c)
+	})
+	return nil
+}
+
+// Send hands a message sent by the client to its Member, waiting until the Member reads it
+func (c *Channel) Send(message []byte) error {
+	select {
+	case c.sent <- message:
+		return nil
+	case <-c.parted:
+		return ErrNotOnChannel
+	case <-c.closed:
+		return ErrNotOnChannel
+	}
+}
+
+// part leaves the channel
+func (c *Channel) part() {
+	c.partOnce.Do(func() {
+		close(c.parted)
+	})
+}
+
+func (c *Channel) hasParted() bool {
+	select {
+	case <-c.parted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,462 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+// client is a connection from an IRC client, which may join any number of channels
+type client struct {
+	server *Server
+	conn   net.Conn
+	host   string
+
+	writeMu sync.Mutex
+
+	mu         sync.Mutex
+	nick       string
+	user       string
+	registered bool
+	channels   map[string]*Channel
+}
+
+func newClient(server *Server, conn net.Conn) *client {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		host = conn.RemoteAddr().String()
+	}
+	return &client{
+		server:   server,
+		conn:     conn,
+		host:     host,
+		channels: make(map[string]*Channel),
+	}
+}
+
+// serve handles the lines sent by the client until it quits or the connection fails
+func (c *client) serve() {
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		c.quit()
+	}()
+	go c.ping(done)
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 512), maxLineLength)
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(2 * pingInterval)); err != nil {
+			return
+		}
+		if !scanner.Scan() {
+			return
+		}
+		line, ok := ParseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if !c.handle(line) {
+			return
+		}
+	}
+}
+
+// ping pings the client every pingInterval so idle connections stay open, and dead ones time out
+func (c *client) ping(done <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.write(Line{Command: "PING", Params: []string{ServerName}})
+		case <-done:
+			return
+		}
+	}
+}
+
+// handle handles a line sent by the client, returning false once the client quits
+func (c *client) handle(line Line) bool {
+	switch line.Command {
+	case "PING":
+		c.write(Line{Prefix: ServerName, Command: "PONG", Params: append([]string{ServerName}, line.Params...)})
+		return true
+	case "PONG":
+		return true
+	case "QUIT":
+		return false
+	case "NICK":
+		c.setNick(line)
+		return true
+	case "USER":
+		c.setUser(line)
+		return true
+	case "JOIN", "PART", "PRIVMSG", "NAMES":
+	default:
+		c.reply(errUnknownCommand, line.Command, "Unknown command")
+		return true
+	}
+
+	if !c.isRegistered() {
+		c.reply(errNotRegistered, "You have not registered")
+		return true
+	}
+	if len(line.Params) == 0 {
+		switch line.Command {
+		case "PRIVMSG":
+			c.reply(errNoRecipient, "No recipient given (PRIVMSG)")
+		case "NAMES":
+			c.reply(rplEndOfNames, "*", "End of /NAMES list")
+		default:
+			c.reply(errNeedMoreParams, line.Command, "Not enough parameters")
+		}
+		return true
+	}
+
+	switch line.Command {
+	case "JOIN":
+		if line.Params[0] == "0" {
+			c.partAll()
+			return true
+		}
+		for _, name := range strings.Split(line.Params[0], ",") {
+			c.join(name)
+		}
+	case "PART":
+		reason := ""
+		if len(line.Params) > 1 {
+			reason = line.Params[1]
+		}
+		for _, name := range strings.Split(line.Params[0], ",") {
+			c.part(name, reason)
+		}
+	case "PRIVMSG":
+		if len(line.Params) < 2 || line.Params[1] == "" {
+			c.reply(errNoTextToSend, "No text to send")
+			return true
+		}
+		for _, target := range strings.Split(line.Params[0], ",") {
+			c.privmsg(target, line.Params[1])
+		}
+	case "NAMES":
+		for _, name := range strings.Split(line.Params[0], ",") {
+			c.names(name, false)
+		}
+	}
+	return true
+}
+
+func (c *client) setNick(line Line) {
+	if len(line.Params) == 0 || line.Params[0] == "" {
+		c.reply(errNoNicknameGiven, "No nickname given")
+		return
+	}
+	nick := line.Params[0]
+	if !ValidNick(nick) {
+		c.reply(errErroneusNickname, nick, "Erroneous nickname")
+		return
+	}
+
+	c.mu.Lock()
+	prefix := c.prefixLocked()
+	c.nick = nick
+	registered := c.registered
+	c.mu.Unlock()
+
+	if registered {
+		c.write(Line{Prefix: prefix, Command: "NICK", Params: []string{nick}})
+		return
+	}
+	c.register()
+}
+
+func (c *client) setUser(line Line) {
+	if len(line.Params) < 4 || line.Params[0] == "" {
+		c.reply(errNeedMoreParams, "USER", "Not enough parameters")
+		return
+	}
+
+	c.mu.Lock()
+	if c.registered {
+		c.mu.Unlock()
+		c.reply(errAlreadyRegistred, "You may not reregister")
+		return
+	}
+	c.user = line.Params[0]
+	c.mu.Unlock()
+	c.register()
+}
+
+// register welcomes the client once it has sent both NICK & USER
+func (c *client) register() {
+	c.mu.Lock()
+	if c.registered || c.nick == "" || c.user == "" {
+		c.mu.Unlock()
+		return
+	}
+	c.registered = true
+	prefix := c.prefixLocked()
+	c.mu.Unlock()
+
+	c.reply(rplWelcome, "Welcome to sharechat.dev "+prefix)
+	c.reply(rplYourHost, "Your host is "+ServerName)
+	c.reply(rplCreated, "This server was created "+c.server.created.Format(time.RFC1123))
+	c.reply(rplMyInfo, ServerName, "sharechat", "i", "n")
+	c.reply(errNoMotd, "MOTD File is missing")
+}
+
+func (c *client) isRegistered() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.registered
+}
+
+// join joins the room of a channel as a new Member
+func (c *client) join(name string) {
+	roomID := strings.TrimPrefix(name, "#")
+	if !strings.HasPrefix(name, "#") || roomID == "" {
+		c.reply(errNoSuchChannel, name, "No such channel")
+		return
+	}
+	if _, ok := c.channel(name); ok {
+		return
+	}
+
+	channel := newChannel(c, roomID)
+	member, err := c.server.controller.JoinRoom(context.Background(), roomID, channel)
+	if err != nil {
+		var publishErr *sharechat.ErrFailedToPublish
+		switch {
+		case errors.As(err, &publishErr):
+			log.Printf("room %s is serving, but member failed to publish: %v", roomID, publishErr)
+		case errors.Is(err, sharechat.ErrRoomNotFound):
+			c.reply(errNoSuchChannel, name, "No such channel")
+			return
+		default:
+			log.Printf("failed to serve room %s: %v", roomID, err)
+			c.reply(errUnavailResource, name, "Channel is temporarily unavailable")
+			return
+		}
+	}
+
+	channel.memberID = member.ID
+	c.mu.Lock()
+	c.channels[name] = channel
+	prefix := c.prefixLocked()
+	c.mu.Unlock()
+
+	c.write(Line{Prefix: prefix, Command: "JOIN", Params: []string{name}})
+	c.names(name, true)
+	close(channel.joined)
+}
+
+// part leaves the channel, so its Member leaves the room
+func (c *client) part(name, reason string) {
+	channel, ok := c.channel(name)
+	if !ok {
+		c.reply(errNotOnChannel, name, "You're not on that channel")
+		return
+	}
+
+	c.removeChannel(channel)
+	channel.part()
+
+	params := []string{name}
+	if reason != "" {
+		params = append(params, reason)
+	}
+	c.write(Line{Prefix: c.prefix(), Command: "PART", Params: params})
+}
+
+func (c *client) partAll() {
+	for _, name := range c.channelNames() {
+		c.part(name, "")
+	}
+}
+
+// privmsg sends a message to the room of a channel; private messages are not supported
+func (c *client) privmsg(target, text string) {
+	if !strings.HasPrefix(target, "#") {
+		c.reply(errNoSuchNick, target, "No such nick/channel")
+		return
+	}
+
+	channel, ok := c.channel(target)
+	if !ok {
+		c.reply(errCannotSendToChan, target, "Cannot send to channel")
+		return
+	}
+	// the text is always chat, even if it looks like an envelope
+	message, err := sharechat.EncodeEnvelope(sharechat.ChatOp, sharechat.Payload{Message: text})
+	if err != nil {
+		log.Printf("failed to encode message for %s: %v", target, err)
+		return
+	}
+	if err := channel.Send(message); err != nil {
+		c.reply(errCannotSendToChan, target, "Cannot send to channel")
+	}
+}
+
+// names lists the nicks of the Members of a channel's room, after the room's name as the topic if asked
+func (c *client) names(name string, topic bool) {
+	channel, ok := c.channel(name)
+	if !ok {
+		c.reply(rplEndOfNames, name, "End of /NAMES list")
+		return
+	}
+
+	room, err := c.server.controller.GetRoom(context.Background(), channel.roomID)
+	if err != nil {
+		log.Printf("failed to get room %s: %v", channel.roomID, err)
+		c.reply(rplEndOfNames, name, "End of /NAMES list")
+		return
+	}
+
+	if topic {
+		c.reply(rplTopic, name, room.RoomName)
+	}
+	var nicks []string
+	length := 0
+	for _, member := range room.Members {
+		nick := Nick(member.Name)
+		if member.ID == channel.memberID {
+			nick = c.currentNick()
+		}
+		if length+len(nick) > maxNamesLength {
+			c.reply(rplNameReply, "=", name, strings.Join(nicks, " "))
+			nicks, length = nil, 0
+		}
+		nicks = append(nicks, nick)
+		length += len(nick) + 1
+	}
+	if len(nicks) > 0 {
+		c.reply(rplNameReply, "=", name, strings.Join(nicks, " "))
+	}
+	c.reply(rplEndOfNames, name, "End of /NAMES list")
+}
+
+// relay writes a message of a channel's room to the client
+func (c *client) relay(channel *Channel, message sharechat.Message) {
+	own := message.MemberID == channel.memberID
+	prefix := Nick(message.MemberName) + "!member@" + ServerName
+
+	switch message.Type {
+	case sharechat.Chat:
+		if own {
+			// IRC clients show the messages they send themselves
+			return
+		}
+		for _, text := range strings.Split(message.Message, "\n") {
+			if text = strings.TrimSuffix(text, "\r"); text != "" {
+				c.write(Line{Prefix: prefix, Command: "PRIVMSG", Params: []string{channel.name, text}})
+			}
+		}
+	case sharechat.MemberJoined:
+		// the client was sent its own JOIN when it joined
+		if !own {
+			c.write(Line{Prefix: prefix, Command: "JOIN", Params: []string{channel.name}})
+		}
+	case sharechat.MemberLeft:
+		c.write(Line{Prefix: prefix, Command: "PART", Params: []string{channel.name}})
+	case sharechat.SendFailed, sharechat.ProtocolError:
+		c.write(Line{Prefix: ServerName, Command: "NOTICE", Params: []string{channel.name, message.Message}})
+	case sharechat.RoomExpired:
+		c.write(Line{Prefix: ServerName, Command: "KICK", Params: []string{channel.name, c.currentNick(), message.Message}})
+	default:
+		// IRC has no way to edit, delete, react to or acknowledge messages, nor to show who is typing
+	}
+}
+
+// quit leaves every channel & closes the connection
+func (c *client) quit() {
+	for _, channel := range c.allChannels() {
+		c.removeChannel(channel)
+		channel.part()
+	}
+	c.write(Line{Command: "ERROR", Params: []string{"Closing link"}})
+	if err := c.conn.Close(); err != nil {
+		log.Printf("failed to close IRC connection from %s: %v", c.host, err)
+	}
+}
+
+func (c *client) channel(name string) (*Channel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	channel, ok := c.channels[name]
+	return channel, ok
+}
+
+func (c *client) channelNames() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.channels))
+	for name := range c.channels {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (c *client) allChannels() []*Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	channels := make([]*Channel, 0, len(c.channels))
+	for _, channel := range c.channels {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// removeChannel forgets a channel, unless the client has since joined it again
+func (c *client) removeChannel(channel *Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.channels[channel.name] == channel {
+		delete(c.channels, channel.name)
+	}
+}
+
+func (c *client) currentNick() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nick
+}
+
+func (c *client) prefix() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prefixLocked()
+}
+
+// prefixLocked returns the client's prefix, e.g. "nick!user@host"; c.mu must be held
+func (c *client) prefixLocked() string {
+	return c.nick + "!" + c.user + "@" + c.host
+}
+
+// reply writes a numeric reply to the client
+func (c *client) reply(numeric string, params ...string) {
+	nick := c.currentNick()
+	if nick == "" {
+		nick = "*"
+	}
+	c.write(Line{Prefix: ServerName, Command: numeric, Params: append([]string{nick}, params...)})
+}
+
+// write writes a line to the client. If writing fails the connection is closed,
+// so the client stops being served & leaves its channels.
+func (c *client) write(line Line) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write([]byte(line.String() + "\r\n")); err != nil {
+		_ = c.conn.Close()
+	}
+}
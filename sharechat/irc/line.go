@@ -0,0 +1,125 @@
+package irc
+
+import (
+	"strings"
+)
+
+// maxNickLength bounds the nicks clients may choose
+const maxNickLength = 30
+
+// Line is a message of the IRC protocol, e.g. ":nick!user@host PRIVMSG #channel :hello world"
+type Line struct {
+	Prefix  string
+	Command string
+	Params  []string
+}
+
+// ParseLine parses a line sent by a client, ignoring any IRCv3 message tags.
+// It returns false if the line holds no command.
+func ParseLine(raw string) (Line, bool) {
+	raw = strings.TrimRight(raw, "\r\n")
+	if strings.HasPrefix(raw, "@") {
+		_, raw, _ = strings.Cut(raw, " ")
+	}
+
+	var line Line
+	raw = strings.TrimLeft(raw, " ")
+	if strings.HasPrefix(raw, ":") {
+		line.Prefix, raw, _ = strings.Cut(raw[1:], " ")
+	}
+
+	for {
+		raw = strings.TrimLeft(raw, " ")
+		if raw == "" {
+			break
+		}
+		if line.Command != "" && strings.HasPrefix(raw, ":") {
+			line.Params = append(line.Params, raw[1:])
+			break
+		}
+
+		var param string
+		param, raw, _ = strings.Cut(raw, " ")
+		if line.Command == "" {
+			line.Command = strings.ToUpper(param)
+		} else {
+			line.Params = append(line.Params, param)
+		}
+	}
+
+	return line, line.Command != ""
+}
+
+// String formats the line as it is written to a client, without its trailing CRLF
+func (l Line) String() string {
+	var b strings.Builder
+	if l.Prefix != "" {
+		b.WriteString(":")
+		b.WriteString(l.Prefix)
+		b.WriteString(" ")
+	}
+	b.WriteString(l.Command)
+	for i, param := range l.Params {
+		param = sanitize(param)
+		b.WriteString(" ")
+		if i == len(l.Params)-1 && (param == "" || param[0] == ':' || strings.Contains(param, " ")) {
+			b.WriteString(":")
+		}
+		b.WriteString(param)
+	}
+	return b.String()
+}
+
+// sanitize replaces the characters that would end a line early
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', 0:
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+func isNickSpecial(r rune) bool {
+	return strings.ContainsRune("[]\\`_^{|}", r)
+}
+
+func isLetter(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+// ValidNick reports whether a client may use nick, following RFC 2812
+func ValidNick(nick string) bool {
+	if nick == "" || len(nick) > maxNickLength {
+		return false
+	}
+	for i, r := range nick {
+		if isLetter(r) || isNickSpecial(r) {
+			continue
+		}
+		if i > 0 && (isDigit(r) || r == '-') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// Nick returns the nick of a Member, e.g. "Champagne_Mind" for the Member named "Champagne Mind"
+func Nick(name string) string {
+	nick := strings.Map(func(r rune) rune {
+		if isLetter(r) || isDigit(r) || isNickSpecial(r) || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+	if nick == "" || !ValidNick(nick[:1]) {
+		nick = "_" + nick
+	}
+	return nick
+}
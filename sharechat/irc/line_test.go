@@ -0,0 +1,88 @@
+//go:build unit || all
+
+package irc_test
+
+import (
+	"testing"
+
+	"github.com/soggycactus/sharechat.dev/sharechat/irc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := map[string]struct {
+		raw  string
+		line irc.Line
+		ok   bool
+	}{
+		"command": {
+			raw:  "quit\r\n",
+			line: irc.Line{Command: "QUIT"},
+			ok:   true,
+		},
+		"params": {
+			raw:  "USER guest 0 * :Real Name",
+			line: irc.Line{Command: "USER", Params: []string{"guest", "0", "*", "Real Name"}},
+			ok:   true,
+		},
+		"prefix & tags": {
+			raw:  "@time=2023-01-01T00:00:00Z :nick!user@host PRIVMSG #room :hello :) world",
+			line: irc.Line{Prefix: "nick!user@host", Command: "PRIVMSG", Params: []string{"#room", "hello :) world"}},
+			ok:   true,
+		},
+		"empty trailing": {
+			raw:  "PART #room :",
+			line: irc.Line{Command: "PART", Params: []string{"#room", ""}},
+			ok:   true,
+		},
+		"extra spaces": {
+			raw:  "JOIN   #a,#b  ",
+			line: irc.Line{Command: "JOIN", Params: []string{"#a,#b"}},
+			ok:   true,
+		},
+		"empty": {
+			raw: "\r\n",
+			ok:  false,
+		},
+		"prefix only": {
+			raw: ":nick!user@host",
+			ok:  false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			line, ok := irc.ParseLine(test.raw)
+			assert.Equal(t, test.ok, ok)
+			if test.ok {
+				assert.Equal(t, test.line, line)
+			}
+		})
+	}
+}
+
+func TestLineString(t *testing.T) {
+	line := irc.Line{Prefix: "server", Command: "PRIVMSG", Params: []string{"#room", "hello\r\nQUIT"}}
+	assert.Equal(t, ":server PRIVMSG #room :hello  QUIT", line.String())
+
+	line = irc.Line{Command: "NICK", Params: []string{"guest"}}
+	assert.Equal(t, "NICK guest", line.String())
+
+	line = irc.Line{Command: "PRIVMSG", Params: []string{"#room", ":)"}}
+	assert.Equal(t, "PRIVMSG #room ::)", line.String())
+
+	line = irc.Line{Command: "PART", Params: []string{"#room", ""}}
+	assert.Equal(t, "PART #room :", line.String())
+}
+
+func TestNick(t *testing.T) {
+	assert.Equal(t, "Champagne_Mind", irc.Nick("Champagne Mind"))
+	assert.Equal(t, "_9lives", irc.Nick("9lives"))
+	assert.Equal(t, "_", irc.Nick(""))
+	assert.True(t, irc.ValidNick(irc.Nick("Café Olé")))
+
+	assert.True(t, irc.ValidNick("guest[away]"))
+	assert.False(t, irc.ValidNick("-guest"))
+	assert.False(t, irc.ValidNick("two words"))
+	assert.False(t, irc.ValidNick(""))
+}
@@ -0,0 +1,69 @@
+package irc
+
+import (
+	"net"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	// ServerName prefixes the lines written by the server
+	ServerName = "sharechat.dev"
+	// pingInterval is how often clients are pinged; a client that sends nothing
+	// for two intervals is disconnected
+	pingInterval = 2 * time.Minute
+	// writeTimeout bounds how long writing a line to a client may take
+	writeTimeout = 10 * time.Second
+	// maxLineLength bounds the lines clients send, including IRCv3 message tags
+	maxLineLength = 8192
+	// maxNamesLength bounds the nicks listed by a single RPL_NAMREPLY
+	maxNamesLength = 400
+)
+
+// numeric replies, as defined by RFC 2812
+const (
+	rplWelcome          = "001"
+	rplYourHost         = "002"
+	rplCreated          = "003"
+	rplMyInfo           = "004"
+	rplTopic            = "332"
+	rplNameReply        = "353"
+	rplEndOfNames       = "366"
+	errNoSuchNick       = "401"
+	errNoSuchChannel    = "403"
+	errCannotSendToChan = "404"
+	errNoRecipient      = "411"
+	errNoTextToSend     = "412"
+	errUnknownCommand   = "421"
+	errNoMotd           = "422"
+	errNoNicknameGiven  = "431"
+	errErroneusNickname = "432"
+	errUnavailResource  = "437"
+	errNotOnChannel     = "442"
+	errNotRegistered    = "451"
+	errNeedMoreParams   = "461"
+	errAlreadyRegistred = "462"
+)
+
+// Server is a gateway letting IRC clients chat in rooms. Each room is the channel #<room-id>,
+// which clients join as a Member of the room; the other Members of the room are shown by their names.
+type Server struct {
+	controller *sharechat.Controller
+	created    time.Time
+}
+
+func NewServer(controller *sharechat.Controller) *Server {
+	return &Server{controller: controller, created: time.Now()}
+}
+
+// Serve accepts IRC clients on the listener until it fails
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go newClient(s, conn).serve()
+	}
+}
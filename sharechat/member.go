@@ -18,7 +18,10 @@ type Member struct {
 	ID     string `json:"id" db:"member_id"`
 	Name   string `json:"name" db:"name"`
 	RoomID string `json:"roomId" db:"room_id"`
-	conn   Connection
+	// Cluster is the deployment a member replicated by a Bridge joined in;
+	// it tags the member's joined & left messages
+	Cluster string `json:"-" db:"-"`
+	conn    Connection
 	// inbound receives Messages from Room
 	inbound chan Message
 	// outbound forwards Messages to the Controller
@@ -28,7 +31,7 @@ type Member struct {
 	readyBroadcast chan struct{}
 	// startBroadcast allows the Member to begin sending messages
 	startBroadcast chan struct{}
-	// channels to shut down the goroutines
+	// stopBroadcast is closed to shut down the Broadcast & Publish goroutines
 	stopBroadcast chan struct{}
 	// callbacks for synchronizing goroutines during tests
 	callbackListen func()
@@ -36,6 +39,8 @@ type Member struct {
 	closeInbound *sync.Once
 	// sync.Once to safely close outbound
 	closeOutbound *sync.Once
+	// sync.Once to safely close stopBroadcast
+	closeBroadcast *sync.Once
 }
 
 func NewMember(name string, roomID string, conn Connection) *Member {
@@ -53,15 +58,20 @@ func NewMember(name string, roomID string, conn Connection) *Member {
 		callbackListen: func() {},
 		closeInbound:   new(sync.Once),
 		closeOutbound:  new(sync.Once),
+		closeBroadcast: new(sync.Once),
 	}
 }
 
-// Listen receives messages from the Room and forwards them to the websocket connection
+// Listen receives messages from the Room and forwards them to the websocket connection.
+// Once inbound is closed the connection is closed as well.
 func (m *Member) Listen() {
 	m.readyListen <- struct{}{}
 	for {
 		message, ok := <-m.inbound
 		if !ok {
+			if err := m.conn.Close(); err != nil {
+				log.Printf("failed to close connection for Member %s: %v", m.Name, err)
+			}
 			m.callbackListen()
 			return
 		}
@@ -75,7 +85,11 @@ func (m *Member) Listen() {
 // Broadcast receives messages from the websocket connection and forwards them to the Room
 func (m *Member) Broadcast() {
 	m.readyBroadcast <- struct{}{}
-	<-m.startBroadcast
+	select {
+	case <-m.startBroadcast:
+	case <-m.stopBroadcast:
+		return
+	}
 	for {
 		select {
 		case <-m.stopBroadcast:
@@ -83,19 +97,39 @@ func (m *Member) Broadcast() {
 		default:
 			bytes, err := m.conn.ReadBytes()
 			if err != nil {
+				if m.broadcastStopped() {
+					// the connection was closed on our end, e.g. because the room expired
+					return
+				}
 				if err != ErrExpectedClose {
 					log.Printf("failed to read websocket for member %s: %v", m.Name, err)
 				}
-				m.outbound <- NewMemberLeftMessage(*m)
+				m.send(NewMemberLeftMessage(*m))
 				return
 			}
 
-			message := NewChatMessage(*m, bytes)
-			m.outbound <- message
+			m.send(NewMessageFromBytes(*m, bytes))
 		}
 	}
 }
 
+// send forwards a message to the Controller unless the broadcast has been stopped
+func (m *Member) send(message Message) {
+	select {
+	case m.outbound <- message:
+	case <-m.stopBroadcast:
+	}
+}
+
+func (m *Member) broadcastStopped() bool {
+	select {
+	case <-m.stopBroadcast:
+		return true
+	default:
+		return false
+	}
+}
+
 func (m *Member) Inbound(ctx context.Context, message Message) error {
 	select {
 	case m.inbound <- message:
@@ -137,8 +171,12 @@ func (m *Member) StartBroadcast() {
 	m.startBroadcast <- struct{}{}
 }
 
+// StopBroadcast stops the Broadcast goroutine and the Controller's Publish goroutine for this Member.
+// It is safe to call multiple times.
 func (m *Member) StopBroadcast() {
-	m.stopBroadcast <- struct{}{}
+	m.closeBroadcast.Do(func() {
+		close(m.stopBroadcast)
+	})
 }
 
 func (m *Member) WithCallbackListen(f func()) *Member {
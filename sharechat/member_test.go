@@ -117,3 +117,124 @@ func TestBroadcast(t *testing.T) {
 		})
 	}
 }
+
+func TestBroadcastClientFrames(t *testing.T) {
+	testCases := []struct {
+		name         string
+		frame        []byte
+		expectedType sharechat.MessageType
+		target       string
+		content      string
+		emoji        string
+		parent       string
+	}{
+		{name: "Edit Frame", frame: []byte(`{"type":"edit","id":"abc","message":"fixed"}`), expectedType: sharechat.MessageEdited, target: "abc", content: "fixed"},
+		{name: "Delete Frame", frame: []byte(`{"type":"delete","id":"abc"}`), expectedType: sharechat.MessageDeleted, target: "abc"},
+		{name: "React Frame", frame: []byte(`{"type":"react","id":"abc","emoji":"👍"}`), expectedType: sharechat.ReactionAdded, target: "abc", emoji: "👍"},
+		{name: "Unreact Frame", frame: []byte(`{"type":"unreact","id":"abc","emoji":"👍"}`), expectedType: sharechat.ReactionRemoved, target: "abc", emoji: "👍"},
+		{name: "Reply Frame", frame: []byte(`{"type":"reply","id":"abc","message":"agreed"}`), expectedType: sharechat.Chat, content: "agreed", parent: "abc"},
+		{name: "React Frame Missing Emoji", frame: []byte(`{"type":"react","id":"abc"}`), expectedType: sharechat.Chat, content: `{"type":"react","id":"abc"}`},
+		{name: "Frame Missing ID", frame: []byte(`{"type":"delete"}`), expectedType: sharechat.Chat, content: `{"type":"delete"}`},
+		{name: "Edit Frame Missing Message", frame: []byte(`{"type":"edit","id":"abc"}`), expectedType: sharechat.Chat, content: `{"type":"edit","id":"abc"}`},
+		{name: "Reply Frame Missing Message", frame: []byte(`{"type":"reply","id":"abc","message":""}`), expectedType: sharechat.Chat, content: `{"type":"reply","id":"abc","message":""}`},
+		{name: "Unknown Frame", frame: []byte(`{"type":"dance"}`), expectedType: sharechat.Chat, content: `{"type":"dance"}`},
+		{name: "Plain Text", frame: []byte("{ hello"), expectedType: sharechat.Chat, content: "{ hello"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			connection := mock.NewConnection().WithReadBytesResult(test.frame, nil)
+			member := sharechat.NewMember("test", "test", connection)
+
+			go member.Broadcast()
+			err := member.BroadcastReady(context.Background())
+			if err != nil {
+				t.Fatalf("broadcast is not ready: %v", err)
+			}
+
+			messageChan := make(chan sharechat.Message)
+			go func(done chan sharechat.Message) {
+				done <- member.Outbound()
+			}(messageChan)
+
+			member.StartBroadcast()
+			result := <-messageChan
+			member.StopBroadcast()
+
+			assert.Equal(t, test.expectedType, result.Type, "message should have the frame's type")
+			assert.Equal(t, test.target, result.TargetID, "message should target the frame's message")
+			assert.Equal(t, test.content, result.Message, "message content should be the frame's content")
+			assert.Equal(t, test.emoji, result.Emoji, "message should have the frame's emoji")
+			assert.Equal(t, test.parent, result.ParentID, "message should reply to the frame's message")
+			assert.Equal(t, member.ID, result.MemberID, "message should belong to the member")
+		})
+	}
+}
+
+func TestBroadcastEnvelopes(t *testing.T) {
+	testCases := []struct {
+		name         string
+		frame        []byte
+		expectedType sharechat.MessageType
+		target       string
+		content      string
+		emoji        string
+		parent       string
+		code         sharechat.ProtocolErrorCode
+	}{
+		{name: "Chat", frame: []byte(`{"v":1,"op":"chat","payload":{"message":"hello"}}`), expectedType: sharechat.Chat, content: "hello"},
+		{name: "Edit", frame: []byte(`{"v":1,"op":"edit","payload":{"id":"abc","message":"fixed"}}`), expectedType: sharechat.MessageEdited, target: "abc", content: "fixed"},
+		{name: "Delete", frame: []byte(`{"v":1,"op":"delete","payload":{"id":"abc"}}`), expectedType: sharechat.MessageDeleted, target: "abc"},
+		{name: "React", frame: []byte(`{"v":1,"op":"react","payload":{"id":"abc","emoji":"👍"}}`), expectedType: sharechat.ReactionAdded, target: "abc", emoji: "👍"},
+		{name: "Unreact", frame: []byte(`{"v":1,"op":"unreact","payload":{"id":"abc","emoji":"👍"}}`), expectedType: sharechat.ReactionRemoved, target: "abc", emoji: "👍"},
+		{name: "Reply", frame: []byte(`{"v":1,"op":"reply","payload":{"id":"abc","message":"agreed"}}`), expectedType: sharechat.Chat, content: "agreed", parent: "abc"},
+		{name: "Typing", frame: []byte(`{"v":1,"op":"typing"}`), expectedType: sharechat.MemberTyping},
+		{name: "Ack", frame: []byte(`{"v":1,"op":"ack","payload":{"id":"abc","extra":true}}`), expectedType: sharechat.MessageAcked, target: "abc"},
+		{name: "Unsupported Version", frame: []byte(`{"v":2,"op":"chat","payload":{"message":"hello"}}`), expectedType: sharechat.ProtocolError, code: sharechat.UnsupportedVersion},
+		{name: "Missing Version", frame: []byte(`{"op":"chat","payload":{"message":"hello"}}`), expectedType: sharechat.ProtocolError, code: sharechat.UnsupportedVersion},
+		{name: "Unknown Op", frame: []byte(`{"v":1,"op":"dance"}`), expectedType: sharechat.ProtocolError, code: sharechat.UnknownOp},
+		{name: "Invalid Envelope", frame: []byte(`{"v":"one","op":"chat"}`), expectedType: sharechat.ProtocolError, code: sharechat.InvalidEnvelope},
+		{name: "Payload Not An Object", frame: []byte(`{"v":1,"op":"chat","payload":"hello"}`), expectedType: sharechat.ProtocolError, code: sharechat.InvalidPayload},
+		{name: "Missing ID", frame: []byte(`{"v":1,"op":"delete","payload":{}}`), expectedType: sharechat.ProtocolError, code: sharechat.InvalidPayload},
+		{name: "Missing Emoji", frame: []byte(`{"v":1,"op":"react","payload":{"id":"abc"}}`), expectedType: sharechat.ProtocolError, code: sharechat.InvalidPayload},
+		{name: "Missing Message", frame: []byte(`{"v":1,"op":"chat"}`), expectedType: sharechat.ProtocolError, code: sharechat.InvalidPayload},
+		{name: "Edit Missing Message", frame: []byte(`{"v":1,"op":"edit","payload":{"id":"abc"}}`), expectedType: sharechat.ProtocolError, code: sharechat.InvalidPayload},
+		{name: "Legacy Frame", frame: []byte(`{"type":"delete","id":"abc"}`), expectedType: sharechat.MessageDeleted, target: "abc"},
+		{name: "Plain Text", frame: []byte("hello"), expectedType: sharechat.Chat, content: "hello"},
+		{name: "JSON Text", frame: []byte(`{"message":"hello"}`), expectedType: sharechat.Chat, content: `{"message":"hello"}`},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			connection := mock.NewConnection().WithReadBytesResult(test.frame, nil)
+			member := sharechat.NewMember("test", "test", connection)
+
+			go member.Broadcast()
+			err := member.BroadcastReady(context.Background())
+			if err != nil {
+				t.Fatalf("broadcast is not ready: %v", err)
+			}
+
+			messageChan := make(chan sharechat.Message)
+			go func(done chan sharechat.Message) {
+				done <- member.Outbound()
+			}(messageChan)
+
+			member.StartBroadcast()
+			result := <-messageChan
+			member.StopBroadcast()
+
+			assert.Equal(t, test.expectedType, result.Type, "message should have the envelope's type")
+			assert.Equal(t, test.target, result.TargetID, "message should target the envelope's message")
+			assert.Equal(t, test.emoji, result.Emoji, "message should have the envelope's emoji")
+			assert.Equal(t, test.parent, result.ParentID, "message should reply to the envelope's message")
+			assert.Equal(t, test.code, result.Code, "message should have the error's code")
+			assert.Equal(t, member.ID, result.MemberID, "message should belong to the member")
+			if test.code == "" {
+				assert.Equal(t, test.content, result.Message, "message content should be the envelope's content")
+			} else {
+				assert.NotEmpty(t, result.Message, "error should be described")
+			}
+		})
+	}
+}
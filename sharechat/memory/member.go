@@ -3,12 +3,14 @@ package memory
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/soggycactus/sharechat.dev/sharechat"
 )
 
 type MemberRepo struct {
+	mu          sync.Mutex
 	Members     map[string]sharechat.Member
 	messageRepo sharechat.MessageRepository
 }
@@ -21,18 +23,24 @@ func NewMemberRepo(messageRepo sharechat.MessageRepository) *MemberRepo {
 }
 
 func (m *MemberRepo) InsertMember(ctx context.Context, member sharechat.Member) (*sharechat.Message, error) {
+	m.mu.Lock()
 	m.Members[member.ID] = member
+	m.mu.Unlock()
 	message := sharechat.NewMemberJoinedMessage(member)
 	message.Sent = time.Now()
 	result, err := m.messageRepo.InsertMessage(ctx, message)
 	if err != nil {
+		m.mu.Lock()
 		delete(m.Members, member.ID)
+		m.mu.Unlock()
 		return nil, errors.New("failed to insert member")
 	}
 	return result, nil
 }
 
 func (m *MemberRepo) GetMembersByRoom(ctx context.Context, roomID string) (*[]sharechat.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	members := []sharechat.Member{}
 
 	for _, member := range m.Members {
@@ -51,7 +59,19 @@ func (m *MemberRepo) DeleteMember(ctx context.Context, member sharechat.Member)
 	if err != nil {
 		return nil, errors.New("failed to delete member")
 	}
+	m.mu.Lock()
 	delete(m.Members, member.ID)
+	m.mu.Unlock()
 
 	return result, nil
 }
+
+func (m *MemberRepo) deleteRoomMembers(roomID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, member := range m.Members {
+		if member.RoomID == roomID {
+			delete(m.Members, id)
+		}
+	}
+}
@@ -12,19 +12,39 @@ import (
 type MessageRepo struct {
 	mu       sync.Mutex
 	Messages map[string]sharechat.Message
+	// reactions maps message ID -> emoji -> IDs of the members that reacted
+	reactions map[string]map[string]map[string]struct{}
+	// outbox holds the IDs of messages that have not been published yet
+	outbox map[string]*outboxEntry
+	// lastSeq holds the sequence number of the last message inserted in each room
+	lastSeq map[string]int64
 }
 
 func NewMessageRepo() *MessageRepo {
 	return &MessageRepo{
-		Messages: make(map[string]sharechat.Message),
+		Messages:  make(map[string]sharechat.Message),
+		reactions: make(map[string]map[string]map[string]struct{}),
+		outbox:    make(map[string]*outboxEntry),
+		lastSeq:   make(map[string]int64),
 	}
 }
 
 func (m *MessageRepo) InsertMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
 	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if message.ParentID != "" {
+		parent, ok := m.Messages[message.ParentID]
+		if !ok || parent.Type != sharechat.Chat || parent.RoomID != message.RoomID || parent.ParentID != "" {
+			return nil, sharechat.ErrMessageNotFound
+		}
+	}
+
 	message.Sent = time.Now()
+	m.lastSeq[message.RoomID]++
+	message.Seq = m.lastSeq[message.RoomID]
 	m.Messages[message.ID] = message
-	m.mu.Unlock()
+	m.outbox[message.ID] = &outboxEntry{nextAttempt: message.Sent}
 	return &message, nil
 }
 
@@ -38,6 +58,13 @@ func (m *MessageRepo) GetMessages(ctx context.Context, options sharechat.GetMess
 	}
 
 	switch {
+	case options.HasSeq():
+		sort.Slice(sortedMessages, func(i, j int) bool {
+			if options.AfterSeq > 0 {
+				return sortedMessages[i].Seq < sortedMessages[j].Seq
+			}
+			return sortedMessages[j].Seq < sortedMessages[i].Seq
+		})
 	case !options.Before.IsEmpty():
 		sort.Slice(sortedMessages, func(i, j int) bool {
 			return sortedMessages[j].Sent.Before(sortedMessages[i].Sent)
@@ -52,12 +79,18 @@ func (m *MessageRepo) GetMessages(ctx context.Context, options sharechat.GetMess
 		})
 	}
 
+	threads := m.threadSummaries()
+
 	messages := []sharechat.Message{}
 	for _, message := range sortedMessages {
 		if options.Limit > 0 && len(messages) >= options.Limit {
 			break
 		}
 
+		if !options.IncludeReplies && message.ParentID != options.ThreadID {
+			continue
+		}
+
 		if message.ID == options.Before.ID || message.ID == options.After.ID {
 			continue
 		}
@@ -74,8 +107,218 @@ func (m *MessageRepo) GetMessages(ctx context.Context, options sharechat.GetMess
 			continue
 		}
 
+		if options.AfterSeq > 0 && message.Seq <= options.AfterSeq {
+			continue
+		}
+
+		if options.BeforeSeq > 0 && message.Seq >= options.BeforeSeq {
+			continue
+		}
+
+		message.Reactions = m.reactionCounts(message.ID)
+		if thread, ok := threads[message.ID]; ok {
+			message.ReplyCount = thread.ReplyCount
+			message.LastReply = thread.LastReply
+		}
 		messages = append(messages, message)
 	}
 
 	return messages, nil
 }
+
+type threadSummary struct {
+	ReplyCount int
+	LastReply  *time.Time
+}
+
+// threadSummaries returns the reply count & last reply timestamp keyed by parent message ID
+func (m *MessageRepo) threadSummaries() map[string]threadSummary {
+	threads := make(map[string]threadSummary)
+	for _, message := range m.Messages {
+		if message.ParentID == "" {
+			continue
+		}
+
+		thread := threads[message.ParentID]
+		thread.ReplyCount++
+		if thread.LastReply == nil || message.Sent.After(*thread.LastReply) {
+			sent := message.Sent
+			thread.LastReply = &sent
+		}
+		threads[message.ParentID] = thread
+	}
+	return threads
+}
+
+func (m *MessageRepo) reactionCounts(messageID string) map[string]int {
+	reactions, ok := m.reactions[messageID]
+	if !ok {
+		return nil
+	}
+
+	counts := make(map[string]int, len(reactions))
+	for emoji, members := range reactions {
+		counts[emoji] = len(members)
+	}
+	return counts
+}
+
+func (m *MessageRepo) UpdateMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.Messages[message.TargetID]
+	if !ok || existing.Type != sharechat.Chat || existing.MemberID != message.MemberID {
+		return nil, sharechat.ErrMessageNotFound
+	}
+
+	editedAt := time.Now()
+	existing.Message = message.Message
+	existing.EditedAt = &editedAt
+	m.Messages[existing.ID] = existing
+
+	return &existing, nil
+}
+
+func (m *MessageRepo) DeleteMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.Messages[message.TargetID]
+	if !ok || existing.Type != sharechat.Chat || existing.MemberID != message.MemberID {
+		return nil, sharechat.ErrMessageNotFound
+	}
+
+	m.deleteMessage(existing.ID)
+	for id, reply := range m.Messages {
+		if reply.ParentID == existing.ID {
+			m.deleteMessage(id)
+		}
+	}
+
+	return &existing, nil
+}
+
+func (m *MessageRepo) AddReaction(ctx context.Context, message sharechat.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	target, ok := m.Messages[message.TargetID]
+	if !ok || target.Type != sharechat.Chat || target.RoomID != message.RoomID {
+		return sharechat.ErrMessageNotFound
+	}
+
+	if _, ok := m.reactions[target.ID]; !ok {
+		m.reactions[target.ID] = make(map[string]map[string]struct{})
+	}
+	if _, ok := m.reactions[target.ID][message.Emoji]; !ok {
+		m.reactions[target.ID][message.Emoji] = make(map[string]struct{})
+	}
+
+	members := m.reactions[target.ID][message.Emoji]
+	if _, ok := members[message.MemberID]; ok {
+		return sharechat.ErrDuplicateReaction
+	}
+	members[message.MemberID] = struct{}{}
+
+	return nil
+}
+
+func (m *MessageRepo) RemoveReaction(ctx context.Context, message sharechat.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, ok := m.reactions[message.TargetID][message.Emoji]
+	if !ok {
+		return sharechat.ErrReactionNotFound
+	}
+	if _, ok := members[message.MemberID]; !ok {
+		return sharechat.ErrReactionNotFound
+	}
+
+	delete(members, message.MemberID)
+	if len(members) == 0 {
+		delete(m.reactions[message.TargetID], message.Emoji)
+	}
+	if len(m.reactions[message.TargetID]) == 0 {
+		delete(m.reactions, message.TargetID)
+	}
+
+	return nil
+}
+
+func (m *MessageRepo) deleteRoomMessages(roomID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, message := range m.Messages {
+		if message.RoomID == roomID {
+			m.deleteMessage(id)
+		}
+	}
+	delete(m.lastSeq, roomID)
+}
+
+// deleteMessage deletes a message along with its reactions & outbox entry; m.mu must be held
+func (m *MessageRepo) deleteMessage(id string) {
+	delete(m.Messages, id)
+	delete(m.reactions, id)
+	delete(m.outbox, id)
+}
+
+// purgeMessages deletes up to limit of the oldest messages exceeding the retention policy of their room,
+// along with the replies to them. Messages of rooms without a policy are kept.
+func (m *MessageRepo) purgeMessages(policies map[string]sharechat.RetentionPolicy, now time.Time, limit int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := []sharechat.Message{}
+	for _, message := range m.Messages {
+		policy, ok := policies[message.RoomID]
+		if !ok {
+			continue
+		}
+
+		if (policy.MaxAge > 0 && now.Sub(message.Sent) > policy.MaxAge) ||
+			(policy.MaxMessages > 0 && message.Seq <= m.lastSeq[message.RoomID]-int64(policy.MaxMessages)) {
+			expired = append(expired, message)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].Sent.Before(expired[j].Sent)
+	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	purged := 0
+	for _, message := range expired {
+		if _, ok := m.Messages[message.ID]; !ok {
+			// already deleted as a reply to a purged message
+			continue
+		}
+
+		m.deleteMessage(message.ID)
+		for id, reply := range m.Messages {
+			if reply.ParentID == message.ID {
+				m.deleteMessage(id)
+			}
+		}
+		purged++
+	}
+
+	return purged
+}
+
+// lastSentByRoom returns the timestamp of the most recent message in each room
+func (m *MessageRepo) lastSentByRoom() map[string]time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lastSent := make(map[string]time.Time)
+	for _, message := range m.Messages {
+		if message.Sent.After(lastSent[message.RoomID]) {
+			lastSent[message.RoomID] = message.Sent
+		}
+	}
+	return lastSent
+}
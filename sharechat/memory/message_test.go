@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/soggycactus/sharechat.dev/sharechat"
 	"github.com/soggycactus/sharechat.dev/sharechat/memory"
@@ -95,3 +96,299 @@ func TestMessageRepo(t *testing.T) {
 	assert.Equal(t, insertedMessages, queriedMessages, "should return the same messages")
 
 }
+
+func TestMessageRepoUpdateDelete(t *testing.T) {
+	repo := memory.NewMessageRepo()
+
+	author := sharechat.NewMember("author", "test", nil)
+	other := sharechat.NewMember("other", "test", nil)
+
+	message, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*author, []byte("helo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.UpdateMessage(context.TODO(), sharechat.NewMessageEditedMessage(*other, message.ID, []byte("hijacked")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only the author should be able to edit")
+
+	edited, err := repo.UpdateMessage(context.TODO(), sharechat.NewMessageEditedMessage(*author, message.ID, []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "hello", edited.Message, "message content should be updated")
+	assert.NotNil(t, edited.EditedAt, "message should be marked as edited")
+	assert.Equal(t, message.Sent, edited.Sent, "sent timestamp should not change")
+
+	_, err = repo.DeleteMessage(context.TODO(), sharechat.NewMessageDeletedMessage(*other, message.ID))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only the author should be able to delete")
+
+	_, err = repo.DeleteMessage(context.TODO(), sharechat.NewMessageDeletedMessage(*author, message.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, messages, "message should be deleted")
+
+	_, err = repo.UpdateMessage(context.TODO(), sharechat.NewMessageEditedMessage(*author, message.ID, []byte("hello?")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "deleted messages cannot be edited")
+}
+
+func TestMessageRepoReactions(t *testing.T) {
+	repo := memory.NewMessageRepo()
+
+	author := sharechat.NewMember("author", "test", nil)
+	other := sharechat.NewMember("other", "test", nil)
+	outsider := sharechat.NewMember("outsider", "elsewhere", nil)
+
+	message, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*author, []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, member := range []*sharechat.Member{author, other} {
+		err := repo.AddReaction(context.TODO(), sharechat.NewReactionAddedMessage(*member, message.ID, "👍"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = repo.AddReaction(context.TODO(), sharechat.NewReactionAddedMessage(*other, message.ID, "🎉"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.AddReaction(context.TODO(), sharechat.NewReactionAddedMessage(*other, message.ID, "👍"))
+	assert.ErrorIs(t, err, sharechat.ErrDuplicateReaction, "members can only react once per emoji")
+
+	err = repo.AddReaction(context.TODO(), sharechat.NewReactionAddedMessage(*outsider, message.ID, "👍"))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "members cannot react to messages in other rooms")
+
+	messages, err := repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]int{"👍": 2, "🎉": 1}, messages[0].Reactions, "reactions should be aggregated")
+
+	err = repo.RemoveReaction(context.TODO(), sharechat.NewReactionRemovedMessage(*other, message.ID, "👍"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.RemoveReaction(context.TODO(), sharechat.NewReactionRemovedMessage(*other, message.ID, "👍"))
+	assert.ErrorIs(t, err, sharechat.ErrReactionNotFound, "reaction should already be removed")
+
+	messages, err = repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]int{"👍": 1, "🎉": 1}, messages[0].Reactions, "removed reaction should not be counted")
+}
+
+func TestMessageRepoThreads(t *testing.T) {
+	repo := memory.NewMessageRepo()
+	member := sharechat.NewMember("test", "test", nil)
+	outsider := sharechat.NewMember("outsider", "other", nil)
+
+	parent, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*member, []byte("parent")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := repo.InsertMessage(context.TODO(), sharechat.NewReplyMessage(*member, parent.ID, []byte("reply")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.InsertMessage(context.TODO(), sharechat.NewReplyMessage(*member, reply.ID, []byte("nested")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "replies cannot be nested")
+
+	_, err = repo.InsertMessage(context.TODO(), sharechat.NewReplyMessage(*outsider, parent.ID, []byte("outsider")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "members cannot reply to messages in other rooms")
+
+	messages, err := repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, messages, 1, "replies should not be returned with top-level messages")
+	assert.Equal(t, 1, messages[0].ReplyCount, "parent should summarize its replies")
+	if assert.NotNil(t, messages[0].LastReply) {
+		assert.True(t, reply.Sent.Equal(*messages[0].LastReply), "last reply should be the newest reply")
+	}
+
+	everything, err := repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test", IncludeReplies: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, everything, 2, "replies should be returned with top-level messages when included")
+
+	thread, err := repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test", ThreadID: parent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, thread, 1, "thread should contain its replies") {
+		assert.Equal(t, reply.ID, thread[0].ID)
+	}
+
+	_, err = repo.DeleteMessage(context.TODO(), sharechat.NewMessageDeletedMessage(*member, parent.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thread, err = repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test", ThreadID: parent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, thread, "replies should be deleted with their parent")
+}
+
+func TestMessageRepoSearch(t *testing.T) {
+	repo := memory.NewMessageRepo()
+	member := sharechat.NewMember("test", "test", nil)
+	outsider := sharechat.NewMember("outsider", "other", nil)
+
+	for _, message := range []sharechat.Message{
+		sharechat.NewChatMessage(*member, []byte("Deploying the API, then the API docs for everyone today")),
+		sharechat.NewChatMessage(*member, []byte("the api is down")),
+		sharechat.NewChatMessage(*member, []byte("apis are hard")),
+		sharechat.NewChatMessage(*outsider, []byte("api in another room")),
+	} {
+		if _, err := repo.InsertMessage(context.TODO(), message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := repo.SearchMessages(context.TODO(), sharechat.SearchMessageOptions{RoomID: "test", Query: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, results, 2, "should only match whole terms in the room") {
+		return
+	}
+	assert.Equal(t, "the api is down", results[0].Message.Message, "denser matches should rank first")
+	assert.Equal(t, "Deploying the <mark>API</mark>, then the <mark>API</mark> docs for everyone today", results[1].Highlight, "every match should be highlighted")
+
+	page, err := repo.SearchMessages(context.TODO(), sharechat.SearchMessageOptions{
+		RoomID: "test",
+		Query:  "api",
+		After:  sharechat.SearchCursor{ID: results[0].ID, Sent: results[0].Sent, Rank: results[0].Rank},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, page, 1, "cursor should skip returned results") {
+		assert.Equal(t, results[1].ID, page[0].ID)
+	}
+
+	results, err = repo.SearchMessages(context.TODO(), sharechat.SearchMessageOptions{RoomID: "test", Query: "API down"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, results, 1, "every term should match")
+}
+
+func TestMessageRepoOutbox(t *testing.T) {
+	repo := memory.NewMessageRepo()
+	member := sharechat.NewMember("test", "test", nil)
+
+	message, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*member, []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	pending, err := repo.ClaimPendingMessages(context.TODO(), now, now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, pending, 1, "inserted messages should be added to the outbox") {
+		assert.Equal(t, message.ID, pending[0].ID)
+		assert.Equal(t, 0, pending[0].Attempts)
+	}
+
+	pending, err = repo.ClaimPendingMessages(context.TODO(), now, now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, pending, "claimed messages should be leased")
+
+	if err := repo.MarkFailed(context.TODO(), message.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = repo.ClaimPendingMessages(context.TODO(), now, now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, pending, 1, "failed messages should be retried") {
+		assert.Equal(t, 1, pending[0].Attempts, "failed attempts should be counted")
+	}
+
+	if err := repo.MarkPublished(context.TODO(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = repo.ClaimPendingMessages(context.TODO(), now.Add(time.Hour), now.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, pending, "published messages should be removed from the outbox")
+
+	deleted, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*member, []byte("oops")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.DeleteMessage(context.TODO(), sharechat.NewMessageDeletedMessage(*member, deleted.ID)); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = repo.ClaimPendingMessages(context.TODO(), time.Now().Add(time.Hour), time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, pending, "deleted messages should be removed from the outbox")
+}
+
+func TestMessageRepoSeq(t *testing.T) {
+	repo := memory.NewMessageRepo()
+	member := sharechat.NewMember("test", "test", nil)
+	outsider := sharechat.NewMember("outsider", "other", nil)
+
+	for i := 1; i <= 5; i++ {
+		message, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*member, []byte(fmt.Sprintf("test %d", i))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, int64(i), message.Seq, "messages should be numbered in order")
+	}
+
+	other, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*outsider, []byte("other room")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), other.Seq, "each room should be numbered separately")
+
+	_, err = repo.InsertMessage(context.TODO(), sharechat.NewReplyMessage(*member, "missing", []byte("orphan")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound)
+
+	next, err := repo.InsertMessage(context.TODO(), sharechat.NewChatMessage(*member, []byte("test 6")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(6), next.Seq, "failed inserts should not leave gaps")
+
+	messages, err := repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test", AfterSeq: 2, BeforeSeq: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, messages, 2, "should return the messages strictly between the sequence numbers") {
+		assert.Equal(t, int64(3), messages[0].Seq, "should return messages in ascending order")
+		assert.Equal(t, int64(4), messages[1].Seq)
+	}
+
+	messages, err = repo.GetMessages(context.TODO(), sharechat.GetMessageOptions{RoomID: "test", BeforeSeq: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, int64(2), messages[0].Seq, "should return messages in descending order")
+	}
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+type outboxEntry struct {
+	attempts    int
+	nextAttempt time.Time
+}
+
+// ClaimPendingMessages implements sharechat.OutboxRepository; messages are added
+// to the outbox by InsertMessage while holding the same lock that stores them.
+func (m *MessageRepo) ClaimPendingMessages(ctx context.Context, due time.Time, leaseUntil time.Time, limit int) ([]sharechat.OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := []sharechat.OutboxMessage{}
+	for id, entry := range m.outbox {
+		if entry.nextAttempt.After(due) {
+			continue
+		}
+		pending = append(pending, sharechat.OutboxMessage{Message: m.Messages[id], Attempts: entry.attempts})
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Sent.Before(pending[j].Sent)
+	})
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	for _, message := range pending {
+		m.outbox[message.ID].nextAttempt = leaseUntil
+	}
+
+	return pending, nil
+}
+
+func (m *MessageRepo) MarkPublished(ctx context.Context, messageID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.outbox, messageID)
+	return nil
+}
+
+func (m *MessageRepo) MarkFailed(ctx context.Context, messageID string, nextAttempt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.outbox[messageID]; ok {
+		entry.attempts++
+		entry.nextAttempt = nextAttempt
+	}
+	return nil
+}
@@ -2,42 +2,143 @@ package memory
 
 import (
 	"context"
-	"errors"
+	"log"
+	"sync"
 
 	"github.com/soggycactus/sharechat.dev/sharechat"
 )
 
+// OverflowPolicy decides what Publish does when a subscriber's buffer is full
+type OverflowPolicy int
+
+const (
+	// Block waits for the subscriber to make room, until the context of Publish is done
+	Block OverflowPolicy = iota
+	// Drop discards the message for that subscriber only
+	Drop
+)
+
+// defaultBufferSize is how many messages each subscriber buffers by default
+const defaultBufferSize = 64
+
+// NewQueue creates an in-process broker with a topic per room. Every subscriber of a room
+// receives each message published to it, through a buffer of its own.
 func NewQueue() *Queue {
-	return &Queue{messages: make(chan sharechat.Message)}
+	return &Queue{
+		topics:     make(map[string]map[*subscriber]struct{}),
+		bufferSize: defaultBufferSize,
+		policy:     Block,
+	}
 }
 
 type Queue struct {
+	mu sync.Mutex
+	// topics maps a room to its subscribers
+	topics     map[string]map[*subscriber]struct{}
+	bufferSize int
+	policy     OverflowPolicy
+}
+
+type subscriber struct {
 	messages chan sharechat.Message
+	// done is closed once the subscriber has unsubscribed
+	done chan struct{}
+}
+
+// WithBufferSize sets how many messages each new subscriber buffers
+func (q *Queue) WithBufferSize(size int) *Queue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.bufferSize = size
+	return q
+}
+
+// WithOverflowPolicy sets what Publish does when a subscriber's buffer is full
+func (q *Queue) WithOverflowPolicy(policy OverflowPolicy) *Queue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.policy = policy
+	return q
 }
 
 func (q *Queue) Ping(ctx context.Context) error {
 	return nil
 }
 
+// Publish delivers the message to every subscriber of its room. Messages published
+// to a room without subscribers are discarded.
 func (q *Queue) Publish(ctx context.Context, message sharechat.Message) error {
-	select {
-	case q.messages <- message:
-		return nil
-	case <-ctx.Done():
-		return errors.New("context deadline exceeded")
+	q.mu.Lock()
+	subscribers := make([]*subscriber, 0, len(q.topics[message.RoomID]))
+	for sub := range q.topics[message.RoomID] {
+		subscribers = append(subscribers, sub)
 	}
+	policy := q.policy
+	q.mu.Unlock()
+
+	for _, sub := range subscribers {
+		if policy == Drop {
+			select {
+			case sub.messages <- message:
+			case <-sub.done:
+			default:
+				log.Printf("dropped message %s for a slow subscriber of room %s", message.ID, message.RoomID)
+			}
+			continue
+		}
+
+		select {
+		case sub.messages <- message:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
 }
 
-func (q *Queue) Subscribe(ctx context.Context, roomID string) (func(chan sharechat.Message, chan struct{}, chan struct{}), error) {
-	return func(controller chan sharechat.Message, done, ready chan struct{}) {
-		ready <- struct{}{}
+// Subscribe registers a subscriber to the room right away, so messages published after
+// Subscribe returns are buffered until they are received from the Subscription.
+func (q *Queue) Subscribe(ctx context.Context, roomID string) (sharechat.Subscription, error) {
+	q.mu.Lock()
+	sub := &subscriber{
+		messages: make(chan sharechat.Message, q.bufferSize),
+		done:     make(chan struct{}),
+	}
+	if _, ok := q.topics[roomID]; !ok {
+		q.topics[roomID] = make(map[*subscriber]struct{})
+	}
+	q.topics[roomID][sub] = struct{}{}
+	q.mu.Unlock()
+
+	feed := sharechat.NewFeed()
+	go func() {
+		defer feed.Stop(nil)
+		defer q.unsubscribe(roomID, sub)
+
 		for {
 			select {
-			case <-done:
+			case <-feed.Done():
 				return
-			case message := <-q.messages:
-				controller <- message
+			case message := <-sub.messages:
+				if !feed.Send(message) {
+					return
+				}
 			}
 		}
-	}, nil
+	}()
+
+	return feed, nil
+}
+
+func (q *Queue) unsubscribe(roomID string, sub *subscriber) {
+	close(sub.done)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.topics[roomID], sub)
+	if len(q.topics[roomID]) == 0 {
+		delete(q.topics, roomID)
+	}
 }
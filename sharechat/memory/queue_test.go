@@ -0,0 +1,130 @@
+//go:build unit || all
+
+package memory_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func subscribe(t *testing.T, queue *memory.Queue, roomID string) sharechat.Subscription {
+	subscription, err := queue.Subscribe(context.Background(), roomID)
+	require.NoError(t, err)
+	return subscription
+}
+
+func receiveMessage(t *testing.T, subscription sharechat.Subscription) sharechat.Message {
+	select {
+	case message := <-subscription.Messages():
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+		return sharechat.Message{}
+	}
+}
+
+func TestQueueTopics(t *testing.T) {
+	queue := memory.NewQueue()
+	ctx := context.Background()
+
+	roomA := subscribe(t, queue, "a")
+	defer roomA.Close()
+	fanout := subscribe(t, queue, "a")
+	defer fanout.Close()
+	roomB := subscribe(t, queue, "b")
+	defer roomB.Close()
+
+	member := sharechat.NewMember("test", "a", nil)
+	message := sharechat.NewChatMessage(*member, []byte("hello"))
+	require.NoError(t, queue.Publish(ctx, message))
+
+	assert.Equal(t, message.ID, receiveMessage(t, roomA).ID, "subscribers of the room should receive the message")
+	assert.Equal(t, message.ID, receiveMessage(t, fanout).ID, "every subscriber of the room should receive the message")
+
+	select {
+	case message := <-roomB.Messages():
+		t.Fatalf("subscribers of other rooms should not receive message %s", message.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, queue.Publish(ctx, sharechat.NewChatMessage(*sharechat.NewMember("test", "c", nil), []byte("hello"))),
+		"publishing to a room without subscribers should not fail")
+}
+
+func TestQueueOverflow(t *testing.T) {
+	member := sharechat.NewMember("test", "a", nil)
+
+	// nothing receives from the subscription: the first message waits to be received,
+	// the second fills the buffer
+	blocking := memory.NewQueue().WithBufferSize(1)
+	unread := subscribe(t, blocking, "a")
+	defer unread.Close()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, blocking.Publish(context.Background(), sharechat.NewChatMessage(*member, []byte("hello"))))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := blocking.Publish(ctx, sharechat.NewChatMessage(*member, []byte("hello")))
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "publishing to a full subscriber should block")
+
+	dropping := memory.NewQueue().WithBufferSize(1).WithOverflowPolicy(memory.Drop)
+	subscription := subscribe(t, dropping, "a")
+	defer subscription.Close()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, dropping.Publish(context.Background(), sharechat.NewChatMessage(*member, []byte{byte('1' + i)})),
+			"publishing to a full subscriber should not block")
+	}
+
+	// at most one message waits to be received & one more fits in the buffer
+	assert.Equal(t, "1", receiveMessage(t, subscription).Message, "the oldest message should be delivered")
+	received := 1
+	for done := false; !done; {
+		select {
+		case <-subscription.Messages():
+			received++
+		case <-time.After(50 * time.Millisecond):
+			done = true
+		}
+	}
+	assert.Less(t, received, 3, "messages that do not fit in the buffer should be dropped")
+}
+
+func TestQueueClose(t *testing.T) {
+	queue := memory.NewQueue().WithBufferSize(1)
+	member := sharechat.NewMember("test", "a", nil)
+	goroutines := runtime.NumGoroutine()
+
+	subscriptions := []sharechat.Subscription{}
+	for i := 0; i < 10; i++ {
+		subscriptions = append(subscriptions, subscribe(t, queue, "a"))
+	}
+	for _, subscription := range subscriptions {
+		require.NoError(t, subscription.Close())
+		require.NoError(t, subscription.Close(), "closing twice should not fail")
+
+		_, ok := <-subscription.Messages()
+		assert.False(t, ok, "messages should be closed")
+		assert.NoError(t, subscription.Err(), "closed subscriptions have not failed")
+	}
+
+	// poll rather than use assert.Eventually, which runs the condition in a goroutine of its own
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "closed subscriptions should not leak goroutines")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.Publish(ctx, sharechat.NewChatMessage(*member, []byte("hello"))),
+			"closed subscriptions should not block publishing")
+	}
+}
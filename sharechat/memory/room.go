@@ -3,33 +3,120 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/soggycactus/sharechat.dev/sharechat"
 )
 
 type RoomRepo struct {
-	Rooms map[string]*sharechat.Room
+	mu          sync.Mutex
+	Rooms       map[string]*sharechat.Room
+	created     map[string]time.Time
+	retention   map[string]sharechat.RetentionPolicy
+	memberRepo  *MemberRepo
+	messageRepo *MessageRepo
 }
 
-func NewRoomRepo() *RoomRepo {
-	rooms := make(map[string]*sharechat.Room)
-	return &RoomRepo{Rooms: rooms}
+// NewRoomRepo creates a RoomRepo; the member & message repositories are
+// needed so that deleting a room also deletes its members & messages.
+func NewRoomRepo(memberRepo *MemberRepo, messageRepo *MessageRepo) *RoomRepo {
+	return &RoomRepo{
+		Rooms:       make(map[string]*sharechat.Room),
+		created:     make(map[string]time.Time),
+		retention:   make(map[string]sharechat.RetentionPolicy),
+		memberRepo:  memberRepo,
+		messageRepo: messageRepo,
+	}
 }
 
 func (m *RoomRepo) InsertRoom(ctx context.Context, room *sharechat.Room) error {
+	m.mu.Lock()
 	m.Rooms[room.ID] = room
+	m.created[room.ID] = time.Now()
+	m.mu.Unlock()
 	return nil
 }
 
 func (m *RoomRepo) GetRoom(ctx context.Context, roomID string) (*sharechat.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if room, ok := m.Rooms[roomID]; !ok {
-		return nil, fmt.Errorf("room %s does not exist", roomID)
+		return nil, fmt.Errorf("room %s does not exist: %w", roomID, sharechat.ErrRoomNotFound)
 	} else {
 		return room, nil
 	}
 }
 
 func (m *RoomRepo) DeleteRoom(ctx context.Context, roomID string) error {
+	m.mu.Lock()
+	_, ok := m.Rooms[roomID]
 	delete(m.Rooms, roomID)
+	delete(m.created, roomID)
+	delete(m.retention, roomID)
+	m.mu.Unlock()
+
+	if !ok {
+		return sharechat.ErrRoomNotFound
+	}
+
+	m.memberRepo.deleteRoomMembers(roomID)
+	m.messageRepo.deleteRoomMessages(roomID)
+	return nil
+}
+
+func (m *RoomRepo) GetInactiveRooms(ctx context.Context, cutoff time.Time) ([]string, error) {
+	m.mu.Lock()
+	candidates := []string{}
+	for roomID, created := range m.created {
+		if created.Before(cutoff) {
+			candidates = append(candidates, roomID)
+		}
+	}
+	m.mu.Unlock()
+
+	lastSent := m.messageRepo.lastSentByRoom()
+
+	roomIDs := []string{}
+	for _, roomID := range candidates {
+		if sent, ok := lastSent[roomID]; ok && !sent.Before(cutoff) {
+			continue
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+
+	return roomIDs, nil
+}
+
+func (m *RoomRepo) GetRoomRetention(ctx context.Context, roomID string) (*sharechat.RetentionPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Rooms[roomID]; !ok {
+		return nil, sharechat.ErrRoomNotFound
+	}
+
+	policy := m.retention[roomID]
+	return &policy, nil
+}
+
+func (m *RoomRepo) SetRoomRetention(ctx context.Context, roomID string, policy sharechat.RetentionPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Rooms[roomID]; !ok {
+		return sharechat.ErrRoomNotFound
+	}
+
+	m.retention[roomID] = policy
 	return nil
 }
+
+func (m *RoomRepo) PurgeMessages(ctx context.Context, policy sharechat.RetentionPolicy, limit int) (int, error) {
+	m.mu.Lock()
+	policies := make(map[string]sharechat.RetentionPolicy, len(m.Rooms))
+	for roomID := range m.Rooms {
+		policies[roomID] = policy.Override(m.retention[roomID])
+	}
+	m.mu.Unlock()
+
+	return m.messageRepo.purgeMessages(policies, time.Now(), limit), nil
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+// SearchMessages matches messages containing every term of the query, ignoring case.
+// Messages are ranked by the share of their terms that match the query.
+func (m *MessageRepo) SearchMessages(ctx context.Context, options sharechat.SearchMessageOptions) ([]sharechat.SearchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	terms := make(map[string]struct{})
+	for _, term := range tokenize(options.Query) {
+		terms[term] = struct{}{}
+	}
+
+	results := []sharechat.SearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	for _, message := range m.Messages {
+		if message.Type != sharechat.Chat || message.RoomID != options.RoomID {
+			continue
+		}
+
+		tokens := tokenize(message.Message)
+		matched := make(map[string]struct{})
+		hits := 0
+		for _, token := range tokens {
+			if _, ok := terms[token]; ok {
+				matched[token] = struct{}{}
+				hits++
+			}
+		}
+		if len(matched) != len(terms) {
+			continue
+		}
+
+		results = append(results, sharechat.SearchResult{
+			Message:   message,
+			Rank:      float64(hits) / float64(len(tokens)),
+			Highlight: highlight(message.Message, terms),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return searchResultBefore(results[i], results[j])
+	})
+
+	page := []sharechat.SearchResult{}
+	for _, result := range results {
+		if options.Limit > 0 && len(page) >= options.Limit {
+			break
+		}
+
+		if !options.After.IsEmpty() && !searchResultBefore(sharechat.SearchResult{
+			Message: sharechat.Message{ID: options.After.ID, Sent: options.After.Sent},
+			Rank:    options.After.Rank,
+		}, result) {
+			continue
+		}
+
+		page = append(page, result)
+	}
+
+	return page, nil
+}
+
+// searchResultBefore orders results by rank, then most recent first
+func searchResultBefore(a, b sharechat.SearchResult) bool {
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
+	if !a.Sent.Equal(b.Sent) {
+		return a.Sent.After(b.Sent)
+	}
+	return a.ID > b.ID
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func tokenize(s string) []string {
+	tokens := strings.FieldsFunc(s, isSeparator)
+	for i, token := range tokens {
+		tokens[i] = strings.ToLower(token)
+	}
+	return tokens
+}
+
+// highlight wraps every word of message found in terms with the highlight markers
+func highlight(message string, terms map[string]struct{}) string {
+	builder := strings.Builder{}
+	start := -1
+	flush := func(end int) {
+		word := message[start:end]
+		if _, ok := terms[strings.ToLower(word)]; ok {
+			builder.WriteString(sharechat.HighlightStart + word + sharechat.HighlightStop)
+		} else {
+			builder.WriteString(word)
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(message); {
+		r, size := utf8.DecodeRuneInString(message[i:])
+		if isSeparator(r) {
+			if start >= 0 {
+				flush(i)
+			}
+			builder.WriteString(message[i : i+size])
+		} else if start < 0 {
+			start = i
+		}
+		i += size
+	}
+	if start >= 0 {
+		flush(len(message))
+	}
+
+	return builder.String()
+}
@@ -3,7 +3,6 @@ package sharechat
 import (
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,8 +12,26 @@ import (
 )
 
 type MessageRepository interface {
+	// InsertMessage saves a message & assigns it the room's next sequence number.
+	// If message.ParentID is set, ErrMessageNotFound is returned unless the parent
+	// is a top-level chat message in the same room.
 	InsertMessage(ctx context.Context, message Message) (*Message, error)
 	GetMessages(ctx context.Context, options GetMessageOptions) ([]Message, error)
+	// UpdateMessage replaces the content of the chat message message.TargetID with message.Message.
+	// ErrMessageNotFound is returned unless the message exists and was sent by message.MemberID.
+	UpdateMessage(ctx context.Context, message Message) (*Message, error)
+	// DeleteMessage deletes the chat message message.TargetID along with any replies to it.
+	// ErrMessageNotFound is returned unless the message exists and was sent by message.MemberID.
+	DeleteMessage(ctx context.Context, message Message) (*Message, error)
+	// AddReaction records message.MemberID reacting to the chat message message.TargetID with message.Emoji.
+	// ErrMessageNotFound is returned unless the message exists in message.RoomID, and
+	// ErrDuplicateReaction is returned if the member already reacted with the same emoji.
+	AddReaction(ctx context.Context, message Message) error
+	// RemoveReaction removes a reaction previously added by message.MemberID.
+	// ErrReactionNotFound is returned if there is no such reaction.
+	RemoveReaction(ctx context.Context, message Message) error
+	// SearchMessages returns the chat messages of a room matching options.Query, best match first.
+	SearchMessages(ctx context.Context, options SearchMessageOptions) ([]SearchResult, error)
 }
 
 type Message struct {
@@ -28,12 +45,28 @@ type Message struct {
 	Message    string      `json:"message" db:"message"`
 	// Sent is generated by the database
 	Sent time.Time `json:"sent" db:"sent"`
-}
-
-// MarshalBinary implements encoding.BinaryMarshaler
-// This is needed to publish Messages to the Queue
-func (m Message) MarshalBinary() ([]byte, error) {
-	return json.Marshal(m)
+	// Seq is assigned by the repository when a message is saved; the messages of a room are
+	// numbered 1, 2, 3... without gaps, so a client can detect & backfill missed messages.
+	// Messages that are not saved, such as edits or reactions, have no sequence number.
+	Seq int64 `json:"seq,omitempty" db:"seq"`
+	// EditedAt is set by the database when a chat message is edited
+	EditedAt *time.Time `json:"editedAt,omitempty" db:"edited_at"`
+	// TargetID is the ID of the message that an edited, deleted or reaction message refers to
+	TargetID string `json:"targetId,omitempty" db:"-"`
+	// Emoji is the reaction added or removed by a reaction message
+	Emoji string `json:"emoji,omitempty" db:"-"`
+	// Reactions holds the number of reactions to a chat message by emoji
+	Reactions map[string]int `json:"reactions,omitempty" db:"-"`
+	// ParentID is the ID of the top-level message a reply belongs to
+	ParentID string `json:"parentId,omitempty" db:"parent_id"`
+	// ReplyCount & LastReply summarize the thread of a top-level message
+	ReplyCount int        `json:"replyCount,omitempty" db:"-"`
+	LastReply  *time.Time `json:"lastReply,omitempty" db:"-"`
+	// Cluster is the deployment a message relayed by a Bridge was sent in;
+	// it is empty for messages sent in this one
+	Cluster string `json:"cluster,omitempty" db:"cluster"`
+	// Code identifies why an Envelope was rejected, for ProtocolError messages
+	Code ProtocolErrorCode `json:"code,omitempty" db:"-"`
 }
 
 type MessageType string
@@ -43,6 +76,18 @@ const (
 	MemberJoined MessageType = "joined"
 	MemberLeft   MessageType = "left"
 	SendFailed   MessageType = "failed"
+	RoomExpired  MessageType = "expired"
+	// Edits, deletions & reactions are events referring to an existing chat message;
+	// they are broadcast to the room but never stored as messages themselves.
+	MessageEdited   MessageType = "edited"
+	MessageDeleted  MessageType = "deleted"
+	ReactionAdded   MessageType = "reacted"
+	ReactionRemoved MessageType = "unreacted"
+	// Typing & acknowledgements are broadcast to the room as they are sent, and never stored
+	MemberTyping MessageType = "typing"
+	MessageAcked MessageType = "acked"
+	// ProtocolError is written back to a member that sent an Envelope that could not be carried out
+	ProtocolError MessageType = "error"
 )
 
 type MessageCursor struct {
@@ -90,12 +135,31 @@ func (m *MessageCursor) DecodeFromString(s string) error {
 type GetMessageOptions struct {
 	Limit  int
 	RoomID string
-	After  MessageCursor
-	Before MessageCursor
+	// ThreadID limits results to the replies of the given message;
+	// when empty, only top-level messages are returned.
+	ThreadID string
+	// IncludeReplies returns replies alongside top-level messages, e.g. to replay everything
+	// sent in a room; it cannot be combined with ThreadID.
+	IncludeReplies bool
+	After          MessageCursor
+	Before         MessageCursor
+	// AfterSeq & BeforeSeq select messages by sequence number, exclusive of both bounds.
+	// They can be combined to fetch an exact range, but not with the After & Before cursors.
+	AfterSeq  int64
+	BeforeSeq int64
 }
 
 var ErrInvalidOptions = errors.New("invalid options:")
 
+// ErrMessageNotFound is returned when a message does not exist or does not belong to the member modifying it
+var ErrMessageNotFound = errors.New("message not found")
+
+// ErrDuplicateReaction is returned when a member reacts to a message with the same emoji twice
+var ErrDuplicateReaction = errors.New("duplicate reaction")
+
+// ErrReactionNotFound is returned when removing a reaction that does not exist
+var ErrReactionNotFound = errors.New("reaction not found")
+
 func (g *GetMessageOptions) Validate() error {
 	if g.Limit < 0 {
 		return errors.Join(ErrInvalidOptions, errors.New("limit must be greater than 0"))
@@ -105,9 +169,27 @@ func (g *GetMessageOptions) Validate() error {
 		return errors.Join(ErrInvalidOptions, errors.New("cannot specify both after and before"))
 	}
 
+	if g.IncludeReplies && g.ThreadID != "" {
+		return errors.Join(ErrInvalidOptions, errors.New("cannot include replies within a thread"))
+	}
+
+	if g.AfterSeq < 0 || g.BeforeSeq < 0 {
+		return errors.Join(ErrInvalidOptions, errors.New("sequence numbers must be greater than 0"))
+	}
+
+	if g.HasSeq() && (!g.After.IsEmpty() || !g.Before.IsEmpty()) {
+		return errors.Join(ErrInvalidOptions, errors.New("cannot combine sequence numbers with cursors"))
+	}
+
 	return nil
 }
 
+// HasSeq reports whether messages are selected by sequence number;
+// results are then ordered by ascending sequence number if AfterSeq is set, descending otherwise.
+func (g *GetMessageOptions) HasSeq() bool {
+	return g.AfterSeq > 0 || g.BeforeSeq > 0
+}
+
 type GetMessagesResult struct {
 	Messages []Message
 	Next     MessageCursor
@@ -124,12 +206,19 @@ func NewChatMessage(member Member, message []byte) Message {
 	}
 }
 
+func NewReplyMessage(member Member, parentID string, message []byte) Message {
+	reply := NewChatMessage(member, message)
+	reply.ParentID = parentID
+	return reply
+}
+
 func NewMemberJoinedMessage(member Member) Message {
 	return Message{
 		ID:         uuid.New().String(),
 		RoomID:     member.RoomID,
 		MemberID:   member.ID,
 		MemberName: member.Name,
+		Cluster:    member.Cluster,
 		Type:       MemberJoined,
 		Message:    fmt.Sprintf("%s joined the room.", member.Name),
 	}
@@ -141,11 +230,92 @@ func NewMemberLeftMessage(member Member) Message {
 		RoomID:     member.RoomID,
 		MemberID:   member.ID,
 		MemberName: member.Name,
+		Cluster:    member.Cluster,
 		Type:       MemberLeft,
 		Message:    fmt.Sprintf("%s left the room.", member.Name),
 	}
 }
 
+func NewMessageEditedMessage(member Member, targetID string, message []byte) Message {
+	return Message{
+		ID:         uuid.New().String(),
+		RoomID:     member.RoomID,
+		MemberID:   member.ID,
+		MemberName: member.Name,
+		Type:       MessageEdited,
+		Message:    string(message),
+		TargetID:   targetID,
+	}
+}
+
+func NewMessageDeletedMessage(member Member, targetID string) Message {
+	return Message{
+		ID:         uuid.New().String(),
+		RoomID:     member.RoomID,
+		MemberID:   member.ID,
+		MemberName: member.Name,
+		Type:       MessageDeleted,
+		TargetID:   targetID,
+	}
+}
+
+func NewReactionAddedMessage(member Member, targetID string, emoji string) Message {
+	return Message{
+		ID:         uuid.New().String(),
+		RoomID:     member.RoomID,
+		MemberID:   member.ID,
+		MemberName: member.Name,
+		Type:       ReactionAdded,
+		TargetID:   targetID,
+		Emoji:      emoji,
+	}
+}
+
+func NewReactionRemovedMessage(member Member, targetID string, emoji string) Message {
+	return Message{
+		ID:         uuid.New().String(),
+		RoomID:     member.RoomID,
+		MemberID:   member.ID,
+		MemberName: member.Name,
+		Type:       ReactionRemoved,
+		TargetID:   targetID,
+		Emoji:      emoji,
+	}
+}
+
+func NewMemberTypingMessage(member Member) Message {
+	return Message{
+		ID:         uuid.New().String(),
+		RoomID:     member.RoomID,
+		MemberID:   member.ID,
+		MemberName: member.Name,
+		Type:       MemberTyping,
+	}
+}
+
+func NewMessageAckedMessage(member Member, targetID string) Message {
+	return Message{
+		ID:         uuid.New().String(),
+		RoomID:     member.RoomID,
+		MemberID:   member.ID,
+		MemberName: member.Name,
+		Type:       MessageAcked,
+		TargetID:   targetID,
+	}
+}
+
+func NewProtocolErrorMessage(member Member, err *ErrProtocol) Message {
+	return Message{
+		ID:         uuid.New().String(),
+		RoomID:     member.RoomID,
+		MemberID:   member.ID,
+		MemberName: member.Name,
+		Type:       ProtocolError,
+		Code:       err.Code,
+		Message:    err.Detail,
+	}
+}
+
 func NewSendFailedMessage(member Member) Message {
 	return Message{
 		ID:         uuid.New().String(),
@@ -156,3 +326,12 @@ func NewSendFailedMessage(member Member) Message {
 		Message:    "failed to send message",
 	}
 }
+
+func NewRoomExpiredMessage(roomID string) Message {
+	return Message{
+		ID:      uuid.New().String(),
+		RoomID:  roomID,
+		Type:    RoomExpired,
+		Message: "room expired",
+	}
+}
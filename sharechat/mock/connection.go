@@ -7,15 +7,20 @@ import (
 )
 
 type Connection struct {
-	readResult  readBytesResult
+	reads       chan readBytesResult
 	writeResult error
 	mu          sync.Mutex
-	sent        bool
 	inbound     map[string]*sharechat.Message
+	closed      chan struct{}
+	closeOnce   sync.Once
 }
 
 func NewConnection() *Connection {
-	return &Connection{sent: false, inbound: make(map[string]*sharechat.Message)}
+	return &Connection{
+		reads:   make(chan readBytesResult, 16),
+		inbound: make(map[string]*sharechat.Message),
+		closed:  make(chan struct{}),
+	}
 }
 
 type readBytesResult struct {
@@ -30,20 +35,36 @@ func (c *Connection) WriteMessage(v sharechat.Message) error {
 	return c.writeResult
 }
 
+// ReadBytes returns the queued read results in order, then blocks until
+// more are queued with Send or the connection is closed.
 func (c *Connection) ReadBytes() ([]byte, error) {
-	c.mu.Lock()
-	if !c.sent {
-		c.sent = true
-		c.mu.Unlock()
-		return c.readResult.bytes, c.readResult.err
+	select {
+	case result := <-c.reads:
+		return result.bytes, result.err
+	case <-c.closed:
+		return nil, sharechat.ErrExpectedClose
 	}
-	c.mu.Unlock()
-	// block forever so we don't keep sending messages
-	select {}
+}
+
+func (c *Connection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+// Closed returns a channel that is closed once Close has been called.
+func (c *Connection) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// Send queues bytes to be returned by a subsequent ReadBytes, as if the client had sent them.
+func (c *Connection) Send(bytes []byte) {
+	c.reads <- readBytesResult{bytes: bytes}
 }
 
 func (c *Connection) WithReadBytesResult(bytes []byte, err error) *Connection {
-	c.readResult = readBytesResult{bytes: bytes, err: err}
+	c.reads <- readBytesResult{bytes: bytes, err: err}
 	return c
 }
 
@@ -52,8 +73,13 @@ func (c *Connection) WithWriteMessageResult(err error) *Connection {
 	return c
 }
 
+// InboundMessages returns a copy of the messages written to the connection, keyed by ID
 func (c *Connection) InboundMessages() map[string]*sharechat.Message {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.inbound
+	inbound := make(map[string]*sharechat.Message, len(c.inbound))
+	for id, message := range c.inbound {
+		inbound[id] = message
+	}
+	return inbound
 }
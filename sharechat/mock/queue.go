@@ -0,0 +1,92 @@
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+// Queue records published messages without delivering them to subscribers
+type Queue struct {
+	mu              sync.Mutex
+	publishResult   error
+	subscribeResult error
+	published       []sharechat.Message
+	// failures ends each subscription with the error sent to it
+	failures []chan error
+}
+
+func NewQueue() *Queue {
+	return &Queue{}
+}
+
+func (q *Queue) Publish(ctx context.Context, message sharechat.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.publishResult != nil {
+		return q.publishResult
+	}
+	q.published = append(q.published, message)
+	return nil
+}
+
+func (q *Queue) Subscribe(ctx context.Context, roomID string) (sharechat.Subscription, error) {
+	q.mu.Lock()
+	err := q.subscribeResult
+	q.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	feed := sharechat.NewFeed()
+	failure := make(chan error, 1)
+	go func() {
+		select {
+		case <-feed.Done():
+			feed.Stop(nil)
+		case err := <-failure:
+			feed.Stop(err)
+		}
+	}()
+
+	q.mu.Lock()
+	q.failures = append(q.failures, failure)
+	q.mu.Unlock()
+	return feed, nil
+}
+
+// FailSubscriptions ends every subscription with err
+func (q *Queue) FailSubscriptions(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, failure := range q.failures {
+		select {
+		case failure <- err:
+		default:
+		}
+	}
+}
+
+// WithPublishResult makes every subsequent Publish return err; a nil err makes them succeed again
+func (q *Queue) WithPublishResult(err error) *Queue {
+	q.mu.Lock()
+	q.publishResult = err
+	q.mu.Unlock()
+	return q
+}
+
+// WithSubscribeResult makes every subsequent Subscribe return err; a nil err makes them succeed again
+func (q *Queue) WithSubscribeResult(err error) *Queue {
+	q.mu.Lock()
+	q.subscribeResult = err
+	q.mu.Unlock()
+	return q
+}
+
+// Published returns a copy of the messages successfully published, in order
+func (q *Queue) Published() []sharechat.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]sharechat.Message{}, q.published...)
+}
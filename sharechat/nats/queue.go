@@ -0,0 +1,210 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
+)
+
+const (
+	// StreamName is the JetStream stream holding the messages of every room
+	StreamName = "SHARECHAT"
+	// subjectPrefix namespaces the subject of each room
+	subjectPrefix = "sharechat.room."
+	// streamMaxAge expires messages a day after they were published; the messages themselves
+	// are kept by the repository
+	streamMaxAge = 24 * time.Hour
+	// duplicateWindow is how long JetStream remembers the ID of a published message,
+	// so a message published again by the outbox relay is only stored once
+	duplicateWindow = 2 * time.Minute
+	// maxAckPending is how many messages may be delivered to a consumer without being acknowledged
+	maxAckPending = 256
+	// defaultAckWait is how long a delivered message waits to be acknowledged before it is redelivered
+	defaultAckWait = 30 * time.Second
+)
+
+func subject(roomID string) string {
+	return subjectPrefix + roomID
+}
+
+// NewQueue creates a Queue on the JetStream of conn, adding the stream it publishes to if it does not
+// exist yet. durable names the consumers of this Queue, and must be unique to the Controller using it
+// & stable across its restarts, such as the node ID.
+func NewQueue(conn *nats.Conn, durable string) (*Queue, error) {
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := js.StreamInfo(StreamName); err != nil {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:       StreamName,
+			Subjects:   []string{subjectPrefix + ">"},
+			MaxAge:     streamMaxAge,
+			Storage:    nats.FileStorage,
+			Duplicates: duplicateWindow,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add stream %s: %w", StreamName, err)
+		}
+	}
+
+	return &Queue{
+		conn:    conn,
+		js:      js,
+		durable: consumerName(durable),
+		encoder: wire.NewEncoder(wire.JSON, ""),
+		ackWait: defaultAckWait,
+		closed:  make(chan struct{}),
+	}, nil
+}
+
+// Queue is a Queue built on NATS JetStream, publishing the messages of each room on a subject of its own.
+// Every room a Controller subscribes to has a durable consumer named after the Queue, so messages are
+// redelivered until they are acknowledged, which happens once the Controller has taken them for its Room.
+// If the Controller stops without closing its subscriptions, it resumes from the first unacknowledged
+// message when it subscribes to the room again after restarting. Closing a subscription deletes its consumer.
+type Queue struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	durable string
+	encoder *wire.Encoder
+	ackWait time.Duration
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// delivery is a message received by a subscription, which is acknowledged once it is delivered
+type delivery struct {
+	message sharechat.Message
+	msg     *nats.Msg
+}
+
+// consumerName replaces the characters not allowed in the name of a consumer
+func consumerName(name string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(name)
+}
+
+// WithEncoder sets how published messages are encoded; messages are decoded whatever their codec
+func (q *Queue) WithEncoder(encoder *wire.Encoder) *Queue {
+	q.encoder = encoder
+	return q
+}
+
+// WithAckWait sets how long a message delivered to a consumer waits to be acknowledged before it is
+// redelivered, such as after the Controller restarted. It applies to the consumers created afterwards.
+func (q *Queue) WithAckWait(ackWait time.Duration) *Queue {
+	q.ackWait = ackWait
+	return q
+}
+
+func (q *Queue) Ping(ctx context.Context) error {
+	return q.conn.FlushWithContext(ctx)
+}
+
+// Close stops delivering messages; every active subscription fails with sharechat.ErrQueueClosed.
+// The connection is left open, and the consumers of the subscriptions are kept.
+func (q *Queue) Close() error {
+	q.closeOnce.Do(func() { close(q.closed) })
+	return nil
+}
+
+func (q *Queue) Publish(ctx context.Context, message sharechat.Message) error {
+	payload, err := q.encoder.Encode(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.js.Publish(subject(message.RoomID), payload, nats.MsgId(message.ID), nats.Context(ctx))
+	return err
+}
+
+// Subscribe delivers the messages published to the room after Subscribe returns, along with any
+// delivered to the consumer of this Queue for the room before that were never acknowledged
+func (q *Queue) Subscribe(ctx context.Context, roomID string) (sharechat.Subscription, error) {
+	select {
+	case <-q.closed:
+		return nil, sharechat.ErrQueueClosed
+	default:
+	}
+
+	// the callback hands messages to the goroutine feeding the subscription until it stops
+	deliveries := make(chan delivery)
+	stopped := make(chan struct{})
+	handle := func(msg *nats.Msg) {
+		envelope, err := wire.Decode(msg.Data)
+		if err != nil {
+			log.Printf("could not decode message on %s: %v", msg.Subject, err)
+			// redelivering a message that cannot be decoded would not help
+			if err := msg.Term(); err != nil {
+				log.Printf("failed to terminate message on %s: %v", msg.Subject, err)
+			}
+			return
+		}
+
+		select {
+		case deliveries <- delivery{message: envelope.Message, msg: msg}:
+		case <-stopped:
+		}
+	}
+
+	sub, err := q.js.Subscribe(subject(roomID), handle,
+		nats.Durable(q.durable+"_"+consumerName(roomID)),
+		nats.DeliverNew(),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.MaxAckPending(maxAckPending),
+		nats.AckWait(q.ackWait),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := sharechat.NewFeed()
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case d := <-deliveries:
+				// acknowledge only once the Controller has the message, so it is redelivered otherwise
+				if !feed.Send(d.message) {
+					q.unsubscribe(roomID, sub)
+					feed.Stop(nil)
+					return
+				}
+				if err := d.msg.Ack(); err != nil {
+					log.Printf("failed to acknowledge message %s: %v", d.message.ID, err)
+				}
+			case <-q.closed:
+				// the consumer is kept, so unacknowledged messages are redelivered once the room
+				// is subscribed to again
+				if err := sub.Drain(); err != nil {
+					log.Printf("failed to drain the subscription to room %s: %v", roomID, err)
+				}
+				feed.Stop(sharechat.ErrQueueClosed)
+				return
+			case <-feed.Done():
+				q.unsubscribe(roomID, sub)
+				feed.Stop(nil)
+				return
+			}
+		}
+	}()
+
+	return feed, nil
+}
+
+// unsubscribe stops the subscription & deletes its consumer
+func (q *Queue) unsubscribe(roomID string, sub *nats.Subscription) {
+	if err := sub.Unsubscribe(); err != nil {
+		log.Printf("failed to unsubscribe from room %s: %v", roomID, err)
+	}
+}
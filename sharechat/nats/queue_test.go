@@ -0,0 +1,124 @@
+//go:build int || all
+
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/nats"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func connect(t *testing.T) *natsgo.Conn {
+	conn, err := natsgo.Connect("nats://0.0.0.0:4222")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestQueue(t *testing.T) {
+	conn := connect(t)
+	defer conn.Close()
+	queue, err := nats.NewQueue(conn, "test")
+	require.NoError(t, err)
+	queue.WithEncoder(wire.NewEncoder(wire.MessagePack, "test"))
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+	require.NoError(t, queue.Ping(ctx))
+
+	member := sharechat.NewMember("test", sharechat.NewRoom("test").ID, nil)
+	subscription, err := queue.Subscribe(ctx, member.RoomID)
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	published := []sharechat.Message{}
+	for i := 0; i < 3; i++ {
+		message := sharechat.NewChatMessage(*member, []byte("hello world!"))
+		require.NoError(t, queue.Publish(ctx, message))
+		published = append(published, message)
+	}
+	// messages published again by the outbox relay are only stored once
+	require.NoError(t, queue.Publish(ctx, published[2]))
+
+	for _, expected := range published {
+		select {
+		case message := <-subscription.Messages():
+			assert.Equal(t, expected.ID, message.ID, "messages should be delivered in order")
+			assert.Equal(t, "hello world!", message.Message, "message content should be same")
+		case <-ctx.Done():
+			t.Fatal("message was not delivered")
+		}
+	}
+
+	select {
+	case message := <-subscription.Messages():
+		t.Fatalf("message %s was delivered twice", message.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestQueueRedeliversAfterRestart(t *testing.T) {
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	// the first Controller stops without acknowledging the message, as its Room never took it
+	conn := connect(t)
+	queue, err := nats.NewQueue(conn, "restart")
+	require.NoError(t, err)
+	queue.WithAckWait(500 * time.Millisecond)
+
+	member := sharechat.NewMember("test", sharechat.NewRoom("test").ID, nil)
+	_, err = queue.Subscribe(ctx, member.RoomID)
+	require.NoError(t, err)
+
+	published := sharechat.NewChatMessage(*member, []byte("hello world!"))
+	require.NoError(t, queue.Publish(ctx, published))
+	require.NoError(t, conn.Flush())
+	conn.Close()
+
+	// after restarting, it resumes its consumer for the room
+	conn = connect(t)
+	defer conn.Close()
+	queue, err = nats.NewQueue(conn, "restart")
+	require.NoError(t, err)
+
+	subscription, err := queue.Subscribe(ctx, member.RoomID)
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	select {
+	case message := <-subscription.Messages():
+		assert.Equal(t, published.ID, message.ID, "unacknowledged messages should be redelivered")
+	case <-ctx.Done():
+		t.Fatal("message was not redelivered")
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	conn := connect(t)
+	defer conn.Close()
+	queue, err := nats.NewQueue(conn, "close")
+	require.NoError(t, err)
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	subscription, err := queue.Subscribe(ctx, sharechat.NewRoom("test").ID)
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Close())
+	for range subscription.Messages() {
+	}
+	assert.ErrorIs(t, subscription.Err(), sharechat.ErrQueueClosed)
+
+	_, err = queue.Subscribe(ctx, sharechat.NewRoom("test").ID)
+	assert.ErrorIs(t, err, sharechat.ErrQueueClosed)
+}
@@ -0,0 +1,63 @@
+package sharechat
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxRepository tracks persisted messages that have not yet been published to the Queue.
+// Repositories add a message to the outbox in the same transaction that persists it,
+// so a saved message is always eventually published, even if the Controller fails to
+// publish it or stops before it can.
+type OutboxRepository interface {
+	// ClaimPendingMessages returns up to limit messages whose next publish attempt is due
+	// at or before due, oldest first. Claimed messages are not due again until leaseUntil,
+	// so concurrent relays do not publish the same message.
+	ClaimPendingMessages(ctx context.Context, due time.Time, leaseUntil time.Time, limit int) ([]OutboxMessage, error)
+	// MarkPublished removes the message from the outbox.
+	MarkPublished(ctx context.Context, messageID string) error
+	// MarkFailed records a failed publish attempt & schedules the next one.
+	MarkFailed(ctx context.Context, messageID string, nextAttempt time.Time) error
+}
+
+// OutboxMessage is a message waiting in the outbox
+type OutboxMessage struct {
+	Message
+	// Attempts is the number of failed attempts to publish the message
+	Attempts int `db:"attempts"`
+}
+
+const (
+	// outboxBatchSize is the maximum number of messages published per relay run
+	outboxBatchSize = 100
+	// outboxLease is how long a relay has to publish the messages it claimed
+	outboxLease = time.Minute
+	// outboxMaxBackoff caps the delay between attempts to publish a message
+	outboxMaxBackoff = 5 * time.Minute
+)
+
+// directOutbox is the OutboxRepository of Controllers without one, which publish messages directly.
+// It never has pending messages, so nothing is retried.
+type directOutbox struct{}
+
+func (directOutbox) ClaimPendingMessages(context.Context, time.Time, time.Time, int) ([]OutboxMessage, error) {
+	return nil, nil
+}
+
+func (directOutbox) MarkPublished(context.Context, string) error { return nil }
+
+func (directOutbox) MarkFailed(context.Context, string, time.Time) error { return nil }
+
+// outboxBackoff returns the delay before the next attempt to publish a message,
+// doubling with every failed attempt.
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 16 {
+		return outboxMaxBackoff
+	}
+
+	backoff := time.Second << attempts
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
@@ -3,48 +3,114 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/soggycactus/sharechat.dev/sharechat"
 )
 
 const (
+	// The room's counter is incremented by the inserting transaction, which holds the room's
+	// row lock until it commits, so sequence numbers are gap-free. Replies are only inserted
+	// if the parent is a top-level chat message in the same room.
 	insertMessageQuery = `
-	INSERT INTO messages (message_id, type, message, room_id, member_id) VALUES ($1,$2,$3,$4,$5) 
-	RETURNING message_id, type, message, room_id, member_id, sent
+	WITH next AS (
+		UPDATE rooms SET last_seq=last_seq+1
+		WHERE room_id=$4::VARCHAR AND ($6::VARCHAR = '' OR EXISTS (
+			SELECT 1 FROM messages p
+			WHERE p.message_id=$6::VARCHAR AND p.room_id=$4::VARCHAR AND p.type='chat' AND p.parent_id IS NULL
+		))
+		RETURNING last_seq
+	)
+	INSERT INTO messages (message_id, type, message, room_id, member_id, parent_id, seq, cluster)
+	SELECT $1::VARCHAR, $2::message_type, $3::VARCHAR, $4::VARCHAR, $5::VARCHAR, NULLIF($6::VARCHAR, ''), last_seq, $7::VARCHAR FROM next
+	RETURNING message_id, type, message, room_id, member_id, sent, COALESCE(parent_id, '') AS parent_id, seq, cluster
 	`
 	getMessagesQuery = `
-	SELECT message_id, type, message, sent, m.room_id, m.member_id, name as member_name 
+	SELECT message_id, type, message, sent, edited_at, m.room_id, m.member_id, COALESCE(parent_id, '') AS parent_id, m.seq, name as member_name 
 	FROM messages m JOIN members b ON m.member_id = b.member_id
 	WHERE 1=1
 	`
+	getThreadSummariesQuery = `
+	SELECT parent_id, COUNT(*) AS reply_count, MAX(sent) AS last_reply FROM messages
+	WHERE parent_id = ANY($1)
+	GROUP BY parent_id
+	`
+	updateMessageQuery = `
+	UPDATE messages SET message=$1, edited_at=NOW()
+	WHERE message_id=$2 AND member_id=$3 AND type='chat'
+	RETURNING message_id, type, message, room_id, member_id, sent, edited_at, seq
+	`
+	addReactionQuery = `
+	INSERT INTO reactions (message_id, member_id, emoji)
+	SELECT message_id, $2, $3 FROM messages WHERE message_id=$1 AND room_id=$4 AND type='chat'
+	ON CONFLICT DO NOTHING
+	`
+	chatMessageExistsQuery = "SELECT EXISTS (SELECT 1 FROM messages WHERE message_id=$1 AND room_id=$2 AND type='chat')"
+	removeReactionQuery    = "DELETE FROM reactions WHERE message_id=$1 AND member_id=$2 AND emoji=$3"
+	getReactionCountsQuery = `
+	SELECT message_id, emoji, COUNT(*) AS count FROM reactions
+	WHERE message_id = ANY($1)
+	GROUP BY message_id, emoji
+	`
+	deleteMessageQuery = `
+	DELETE FROM messages
+	WHERE message_id=$1 AND member_id=$2 AND type='chat'
+	RETURNING message_id, type, message, room_id, member_id, sent, edited_at, seq
+	`
 )
 
-func NewMessageRepository(db *sql.DB, driver string) *MessageRepository {
-	return &MessageRepository{db: db, driver: driver}
+func NewMessageRepository(db *DB) *MessageRepository {
+	return &MessageRepository{db: db}
 }
 
 type MessageRepository struct {
-	db     *sql.DB
-	driver string
+	db *DB
 }
 
 func (m *MessageRepository) InsertMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
-	db := sqlx.NewDb(m.db, m.driver)
+	var result *sharechat.Message
+
+	err := withTransaction(ctx, m.db, func(tx *sqlx.Tx) error {
+		var err error
+		result, err = insertMessage(ctx, m.db, tx, message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// insertMessage inserts message as part of tx & adds it to the outbox to be published
+func insertMessage(ctx context.Context, db *DB, tx *sqlx.Tx, message sharechat.Message) (*sharechat.Message, error) {
 	var result sharechat.Message
 
-	if err := db.QueryRowxContext(
+	if err := db.getContext(
 		ctx,
+		tx,
+		&result,
 		insertMessageQuery,
 		message.ID,
 		message.Type,
 		message.Message,
 		message.RoomID,
 		message.MemberID,
-	).StructScan(&result); err != nil {
+		message.ParentID,
+		message.Cluster,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sharechat.ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	if _, err := db.execContext(ctx, tx, insertOutboxQuery, result.ID); err != nil {
 		return nil, err
 	}
 
@@ -53,68 +119,249 @@ func (m *MessageRepository) InsertMessage(ctx context.Context, message sharechat
 	return &result, nil
 }
 
+func (m *MessageRepository) UpdateMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
+	var result sharechat.Message
+
+	if err := m.db.getContext(
+		ctx,
+		nil,
+		&result,
+		updateMessageQuery,
+		message.Message,
+		message.TargetID,
+		message.MemberID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sharechat.ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	result.MemberName = message.MemberName
+	return &result, nil
+}
+
+func (m *MessageRepository) DeleteMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
+	var result sharechat.Message
+
+	if err := m.db.getContext(
+		ctx,
+		nil,
+		&result,
+		deleteMessageQuery,
+		message.TargetID,
+		message.MemberID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sharechat.ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	result.MemberName = message.MemberName
+	return &result, nil
+}
+
 func (m *MessageRepository) GetMessages(ctx context.Context, options sharechat.GetMessageOptions) ([]sharechat.Message, error) {
-	db := sqlx.NewDb(m.db, m.driver)
-	var result []sharechat.Message
+	result := []sharechat.Message{}
 
-	query, err := m.buildGetMessagesQuery(options)
-	if err != nil {
+	query, args := m.buildGetMessagesQuery(options)
+	if err := m.db.selectContext(ctx, nil, &result, query, args...); err != nil {
 		return nil, err
 	}
 
-	if err := db.SelectContext(ctx, &result, *query); err != nil {
+	if err := m.attachReactions(ctx, result); err != nil {
+		return nil, err
+	}
+
+	if err := m.attachThreadSummaries(ctx, result); err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
 
-func (m *MessageRepository) buildGetMessagesQuery(options sharechat.GetMessageOptions) (*string, error) {
-	builder := strings.Builder{}
-	if _, err := builder.WriteString(getMessagesQuery); err != nil {
-		return nil, err
+type threadSummary struct {
+	ParentID   string    `db:"parent_id"`
+	ReplyCount int       `db:"reply_count"`
+	LastReply  time.Time `db:"last_reply"`
+}
+
+// attachThreadSummaries populates the reply count & last reply timestamp for each message
+func (m *MessageRepository) attachThreadSummaries(ctx context.Context, messages []sharechat.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	var summaries []threadSummary
+	if err := m.db.selectContext(ctx, nil, &summaries, getThreadSummariesQuery, pq.Array(ids)); err != nil {
+		return err
 	}
 
+	byParent := make(map[string]threadSummary, len(summaries))
+	for _, summary := range summaries {
+		byParent[summary.ParentID] = summary
+	}
+
+	for i := range messages {
+		if summary, ok := byParent[messages[i].ID]; ok {
+			lastReply := summary.LastReply
+			messages[i].ReplyCount = summary.ReplyCount
+			messages[i].LastReply = &lastReply
+		}
+	}
+
+	return nil
+}
+
+type reactionCount struct {
+	MessageID string `db:"message_id"`
+	Emoji     string `db:"emoji"`
+	Count     int    `db:"count"`
+}
+
+// attachReactions populates the aggregated reaction counts for each message
+func (m *MessageRepository) attachReactions(ctx context.Context, messages []sharechat.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	var counts []reactionCount
+	if err := m.db.selectContext(ctx, nil, &counts, getReactionCountsQuery, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	byMessage := make(map[string]map[string]int)
+	for _, count := range counts {
+		if _, ok := byMessage[count.MessageID]; !ok {
+			byMessage[count.MessageID] = make(map[string]int)
+		}
+		byMessage[count.MessageID][count.Emoji] = count.Count
+	}
+
+	for i := range messages {
+		messages[i].Reactions = byMessage[messages[i].ID]
+	}
+
+	return nil
+}
+
+func (m *MessageRepository) AddReaction(ctx context.Context, message sharechat.Message) error {
+	result, err := m.db.execContext(ctx, nil, addReactionQuery, message.TargetID, message.MemberID, message.Emoji, message.RoomID)
+	if err != nil {
+		return err
+	}
+
+	added, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if added == 0 {
+		// nothing was inserted, either because the message doesn't exist or the reaction already does
+		var exists bool
+		if err := m.db.getContext(ctx, nil, &exists, chatMessageExistsQuery, message.TargetID, message.RoomID); err != nil {
+			return err
+		}
+		if !exists {
+			return sharechat.ErrMessageNotFound
+		}
+		return sharechat.ErrDuplicateReaction
+	}
+
+	return nil
+}
+
+func (m *MessageRepository) RemoveReaction(ctx context.Context, message sharechat.Message) error {
+	result, err := m.db.execContext(ctx, nil, removeReactionQuery, message.TargetID, message.MemberID, message.Emoji)
+	if err != nil {
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if removed == 0 {
+		return sharechat.ErrReactionNotFound
+	}
+
+	return nil
+}
+
+// queryBuilder numbers placeholders as arguments are added to the query
+type queryBuilder struct {
+	strings.Builder
+	args []interface{}
+}
+
+// arg adds value to the query's arguments & returns its placeholder
+func (q *queryBuilder) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (m *MessageRepository) buildGetMessagesQuery(options sharechat.GetMessageOptions) (string, []interface{}) {
+	builder := queryBuilder{}
+	builder.WriteString(getMessagesQuery)
+
 	// default to 100 records per query
 	if options.Limit == 0 {
 		options.Limit = 100
 	}
 
 	if options.RoomID != "" {
-		if _, err := builder.WriteString(fmt.Sprintf(" AND m.room_id='%s'", options.RoomID)); err != nil {
-			return nil, err
-		}
+		builder.WriteString(" AND m.room_id=" + builder.arg(options.RoomID))
 	}
 
-	switch {
-	case !options.After.IsEmpty():
-		if _, err := builder.WriteString(fmt.Sprintf(" AND (sent, message_id) > ('%s', '%s')", options.After.Sent.Format(time.RFC3339Nano), options.After.ID)); err != nil {
-			return nil, err
-		}
+	if options.ThreadID != "" {
+		builder.WriteString(" AND m.parent_id=" + builder.arg(options.ThreadID))
+	} else if !options.IncludeReplies {
+		builder.WriteString(" AND m.parent_id IS NULL")
+	}
 
-		if _, err := builder.WriteString(" ORDER BY sent ASC, message_id"); err != nil {
-			return nil, err
+	switch {
+	case options.HasSeq():
+		if options.AfterSeq > 0 {
+			builder.WriteString(" AND m.seq > " + builder.arg(options.AfterSeq))
 		}
-	case !options.Before.IsEmpty():
-		if _, err := builder.WriteString(fmt.Sprintf(" AND (sent, message_id) < ('%s', '%s')", options.Before.Sent.Format(time.RFC3339Nano), options.Before.ID)); err != nil {
-			return nil, err
+		if options.BeforeSeq > 0 {
+			builder.WriteString(" AND m.seq < " + builder.arg(options.BeforeSeq))
 		}
 
-		if _, err := builder.WriteString(" ORDER BY sent DESC, message_id"); err != nil {
-			return nil, err
+		if options.AfterSeq > 0 {
+			builder.WriteString(" ORDER BY m.seq ASC")
+		} else {
+			builder.WriteString(" ORDER BY m.seq DESC")
 		}
+	case !options.After.IsEmpty():
+		builder.WriteString(fmt.Sprintf(
+			" AND (sent, message_id) > (%s, %s) ORDER BY sent ASC, message_id",
+			builder.arg(options.After.Sent), builder.arg(options.After.ID),
+		))
+	case !options.Before.IsEmpty():
+		builder.WriteString(fmt.Sprintf(
+			" AND (sent, message_id) < (%s, %s) ORDER BY sent DESC, message_id DESC",
+			builder.arg(options.Before.Sent), builder.arg(options.Before.ID),
+		))
 	default:
-		if _, err := builder.WriteString(" ORDER BY sent DESC, message_id"); err != nil {
-			return nil, err
-		}
+		builder.WriteString(" ORDER BY sent DESC, message_id DESC")
 	}
 
 	if options.Limit > 0 {
-		if _, err := builder.WriteString(fmt.Sprintf(" LIMIT %d", options.Limit)); err != nil {
-			return nil, err
-		}
+		builder.WriteString(" LIMIT " + builder.arg(options.Limit))
 	}
 
-	query := builder.String()
-	return &query, nil
+	return builder.String(), builder.args
 }
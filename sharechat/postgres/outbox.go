@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	insertOutboxQuery = "INSERT INTO outbox (message_id) VALUES ($1)"
+	// claimOutboxQuery leases the due messages so that concurrent relays skip them
+	claimOutboxQuery = `
+	WITH claimed AS (
+		UPDATE outbox SET next_attempt_at=$2
+		WHERE message_id IN (
+			SELECT message_id FROM outbox
+			WHERE next_attempt_at <= $1
+			ORDER BY created_at, message_id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING message_id, attempts, created_at
+	)
+	SELECT m.message_id, m.type, m.message, m.sent, m.edited_at, m.room_id, m.member_id,
+		COALESCE(m.parent_id, '') AS parent_id, m.seq, m.cluster, b.name AS member_name, c.attempts
+	FROM claimed c
+	JOIN messages m ON c.message_id = m.message_id
+	JOIN members b ON m.member_id = b.member_id
+	ORDER BY c.created_at, c.message_id
+	`
+	deleteOutboxQuery     = "DELETE FROM outbox WHERE message_id=$1"
+	rescheduleOutboxQuery = "UPDATE outbox SET attempts=attempts+1, next_attempt_at=$2 WHERE message_id=$1"
+)
+
+func NewOutboxRepository(db *DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// OutboxRepository relays messages that the message & member repositories
+// add to the outbox in the same transaction that inserts them.
+type OutboxRepository struct {
+	db *DB
+}
+
+func (o *OutboxRepository) ClaimPendingMessages(ctx context.Context, due time.Time, leaseUntil time.Time, limit int) ([]sharechat.OutboxMessage, error) {
+	result := []sharechat.OutboxMessage{}
+	if err := o.db.selectContext(ctx, nil, &result, claimOutboxQuery, due, leaseUntil, limit); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (o *OutboxRepository) MarkPublished(ctx context.Context, messageID string) error {
+	_, err := o.db.execContext(ctx, nil, deleteOutboxQuery, messageID)
+	return err
+}
+
+func (o *OutboxRepository) MarkFailed(ctx context.Context, messageID string, nextAttempt time.Time) error {
+	_, err := o.db.execContext(ctx, nil, rescheduleOutboxQuery, messageID, nextAttempt)
+	return err
+}
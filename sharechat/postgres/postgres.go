@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq" // Postgres Driver
+)
+
+const driver = "postgres"
+
+// DB is the connection pool shared by the postgres repositories.
+// Every query is prepared once and the statement is reused for the lifetime of the pool.
+type DB struct {
+	*sqlx.DB
+
+	mu         sync.Mutex
+	statements map[string]*sqlx.Stmt
+}
+
+func NewDB(db *sql.DB) *DB {
+	return &DB{
+		DB:         sqlx.NewDb(db, driver),
+		statements: make(map[string]*sqlx.Stmt),
+	}
+}
+
+// prepare returns the prepared statement for query, bound to tx if it is not nil
+func (d *DB) prepare(ctx context.Context, tx *sqlx.Tx, query string) (*sqlx.Stmt, error) {
+	d.mu.Lock()
+	stmt, ok := d.statements[query]
+	if !ok {
+		var err error
+		stmt, err = d.PreparexContext(ctx, query)
+		if err != nil {
+			d.mu.Unlock()
+			return nil, err
+		}
+		d.statements[query] = stmt
+	}
+	d.mu.Unlock()
+
+	if tx != nil {
+		return tx.StmtxContext(ctx, stmt), nil
+	}
+	return stmt, nil
+}
+
+func (d *DB) getContext(ctx context.Context, tx *sqlx.Tx, dest interface{}, query string, args ...interface{}) error {
+	stmt, err := d.prepare(ctx, tx, query)
+	if err != nil {
+		return err
+	}
+	return stmt.GetContext(ctx, dest, args...)
+}
+
+func (d *DB) selectContext(ctx context.Context, tx *sqlx.Tx, dest interface{}, query string, args ...interface{}) error {
+	stmt, err := d.prepare(ctx, tx, query)
+	if err != nil {
+		return err
+	}
+	return stmt.SelectContext(ctx, dest, args...)
+}
+
+func (d *DB) execContext(ctx context.Context, tx *sqlx.Tx, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := d.prepare(ctx, tx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// Close closes every prepared statement along with the underlying pool
+func (d *DB) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var err error
+	for query, stmt := range d.statements {
+		if closeErr := stmt.Close(); closeErr != nil {
+			err = multierror.Append(err, closeErr)
+		}
+		delete(d.statements, query)
+	}
+
+	if closeErr := d.DB.Close(); closeErr != nil {
+		err = multierror.Append(err, closeErr)
+	}
+	return err
+}
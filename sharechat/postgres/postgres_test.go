@@ -39,10 +39,11 @@ func TestMain(t *testing.M) {
 }
 
 func TestAll(t *testing.T) {
-	db := GetDB()
+	db := postgres.NewDB(GetDB())
+	defer db.Close()
 	ctx := context.Background()
 
-	roomRepo := postgres.NewRoomRepository(db, "postgres")
+	roomRepo := postgres.NewRoomRepository(db)
 	room := sharechat.NewRoom("test")
 	err := roomRepo.InsertRoom(context.Background(), room)
 	if err != nil {
@@ -57,7 +58,7 @@ func TestAll(t *testing.T) {
 	assert.Equal(t, room.ID, insertedRoom.ID, "room should have same ID")
 	assert.Equal(t, room.Name, insertedRoom.Name, "room should have same name")
 
-	memberRepo := postgres.NewMemberRepository(db, "postgres")
+	memberRepo := postgres.NewMemberRepository(db)
 	member := sharechat.NewMember("test", room.ID, nil)
 
 	insertTime := time.Now()
@@ -85,7 +86,7 @@ func TestAll(t *testing.T) {
 
 	assert.True(t, foundMember, "member should be in database")
 
-	messageRepo := postgres.NewMessageRepository(db, "postgres")
+	messageRepo := postgres.NewMessageRepository(db)
 
 	insertTime = time.Now()
 	chatMessage := sharechat.NewChatMessage(*member, []byte("hello world!"))
@@ -102,7 +103,7 @@ func TestAll(t *testing.T) {
 	assert.Equal(t, chatMessage.Type, result.Type)
 	assert.True(t, insertTime.Before(result.Sent), "message timestamp should be more recent")
 
-	testMessages := []sharechat.Message{*joinedMessage, chatMessage}
+	testMessages := []sharechat.Message{*joinedMessage, *result}
 	for i := 0; i < 5; i++ {
 		message, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte(
 			fmt.Sprintf("This is test message %d", i)),
@@ -156,7 +157,259 @@ func TestAll(t *testing.T) {
 		}
 	}
 
+	for i, message := range testMessages {
+		assert.Equal(t, int64(i+1), message.Seq, "messages should be numbered in the order they were inserted")
+	}
+
+	seqRange, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, AfterSeq: 1, BeforeSeq: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, seqRange, 2, "should return the messages strictly between the sequence numbers")
+	assert.Equal(t, testMessages[1].ID, seqRange[0].ID, "should return messages in ascending order")
+	assert.Equal(t, testMessages[2].ID, seqRange[1].ID)
+
+	seqRange, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, BeforeSeq: 3, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, seqRange, 1)
+	assert.Equal(t, int64(2), seqRange[0].Seq, "should return messages in descending order")
+
+	reactable, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("react to me")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, reactable.ID, "👍"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, reactable.ID, "🎉"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, reactable.ID, "👍"))
+	assert.ErrorIs(t, err, sharechat.ErrDuplicateReaction, "members can only react once per emoji")
+
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, "missing", "👍"))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "cannot react to a missing message")
+
+	err = messageRepo.RemoveReaction(ctx, sharechat.NewReactionRemovedMessage(*member, reactable.ID, "🎉"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = messageRepo.RemoveReaction(ctx, sharechat.NewReactionRemovedMessage(*member, reactable.ID, "🎉"))
+	assert.ErrorIs(t, err, sharechat.ErrReactionNotFound, "reaction should already be removed")
+
+	reacted, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, reacted, 1)
+	assert.Equal(t, reactable.ID, reacted[0].ID)
+	assert.Equal(t, map[string]int{"👍": 1}, reacted[0].Reactions, "reactions should be aggregated")
+
+	other := sharechat.NewMember("other", room.ID, nil)
+	editable, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("helo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = messageRepo.UpdateMessage(ctx, sharechat.NewMessageEditedMessage(*other, editable.ID, []byte("hijacked")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only the author should be able to edit")
+
+	edited, err := messageRepo.UpdateMessage(ctx, sharechat.NewMessageEditedMessage(*member, editable.ID, []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "hello", edited.Message, "message content should be updated")
+	require.NotNil(t, edited.EditedAt, "message should be marked as edited")
+
+	_, err = messageRepo.UpdateMessage(ctx, sharechat.NewMessageEditedMessage(*member, joinedMessage.ID, []byte("hello")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only chat messages can be edited")
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*other, editable.ID))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only the author should be able to delete")
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*member, editable.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*member, editable.ID))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "message should already be deleted")
+
+	parent, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("start a thread")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []sharechat.Message
+	for i := 0; i < 2; i++ {
+		reply, err := messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, parent.ID, []byte(
+			fmt.Sprintf("This is reply %d", i)),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, *reply)
+	}
+
+	_, err = messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, replies[0].ID, []byte("nested")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "replies cannot be nested")
+
+	_, err = messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, "missing", []byte("orphan")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "cannot reply to a missing message")
+
+	topLevel, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, topLevel, 1)
+	assert.Equal(t, parent.ID, topLevel[0].ID, "replies should not be returned with top-level messages")
+	assert.Equal(t, 2, topLevel[0].ReplyCount, "parent should summarize its replies")
+	require.NotNil(t, topLevel[0].LastReply, "parent should have a last reply timestamp")
+	assert.True(t, replies[1].Sent.Equal(*topLevel[0].LastReply), "last reply should be the newest reply")
+
+	everything, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, IncludeReplies: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	included := []string{}
+	for _, message := range everything {
+		included = append(included, message.ID)
+	}
+	assert.Contains(t, included, parent.ID, "top-level messages should be returned when replies are included")
+	assert.Contains(t, included, replies[0].ID, "replies should be returned with top-level messages when included")
+	assert.Contains(t, included, replies[1].ID, "replies should be returned with top-level messages when included")
+
+	thread, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, ThreadID: parent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, thread, 2, "thread should contain its replies")
+	assert.Equal(t, replies[1].ID, thread[0].ID, "should return newest reply first")
+	assert.Equal(t, parent.ID, thread[0].ParentID, "replies should reference their parent")
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*member, parent.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thread, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, ThreadID: parent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, thread, "replies should be deleted with their parent")
+
+	for _, content := range []string{
+		"upgrading the kubernetes cluster tonight",
+		"kubernetes kubernetes kubernetes",
+		"who wants lunch?",
+	} {
+		_, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	searchCursor := sharechat.SearchCursor{}
+	var searchResults []sharechat.SearchResult
+	for {
+		page, err := messageRepo.SearchMessages(ctx, sharechat.SearchMessageOptions{
+			Limit:  1,
+			RoomID: room.ID,
+			Query:  "Kubernetes",
+			After:  searchCursor,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		require.Len(t, page, 1, "should only return one result")
+		searchResults = append(searchResults, page...)
+		searchCursor = sharechat.SearchCursor{ID: page[0].ID, Sent: page[0].Sent, Rank: page[0].Rank}
+	}
+	require.Len(t, searchResults, 2, "should return every matching message once")
+	assert.Equal(t, "kubernetes kubernetes kubernetes", searchResults[0].Message.Message, "best match should be first")
+	assert.GreaterOrEqual(t, searchResults[0].Rank, searchResults[1].Rank, "results should be ranked")
+	assert.Contains(t, searchResults[1].Highlight, sharechat.HighlightStart+"kubernetes"+sharechat.HighlightStop, "matches should be highlighted")
+	assert.Equal(t, member.Name, searchResults[1].MemberName, "should have member name on result")
+
+	for _, query := range []string{`"unbalanced`, "NEAR(kubernetes", "'); DROP TABLE messages; --", "lunch*"} {
+		_, err := messageRepo.SearchMessages(ctx, sharechat.SearchMessageOptions{RoomID: room.ID, Query: query})
+		require.NoError(t, err, "query syntax should not break the search")
+	}
+
+	// messages relayed by a Bridge keep their cluster in the outbox, so they are not relayed back
+	bridged := sharechat.NewRoom("bridged")
+	require.NoError(t, roomRepo.InsertRoom(ctx, bridged))
+	replicated := sharechat.NewMember("replicated", bridged.ID, nil)
+	replicated.Cluster = "us"
+	replicatedJoined, err := memberRepo.InsertMember(ctx, *replicated)
+	require.NoError(t, err)
+	relayed := sharechat.NewChatMessage(*replicated, []byte("relayed"))
+	relayed.Cluster = "us"
+	_, err = messageRepo.InsertMessage(ctx, relayed)
+	require.NoError(t, err)
+
 	deleteTime := time.Now()
+	outboxRepo := postgres.NewOutboxRepository(db)
+	now := time.Now()
+	pending, err := outboxRepo.ClaimPendingMessages(ctx, now, now.Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed := map[string]sharechat.OutboxMessage{}
+	for _, message := range pending {
+		claimed[message.ID] = message
+	}
+	require.Contains(t, claimed, joinedMessage.ID, "inserted members should add their joined message to the outbox")
+	assert.Equal(t, member.Name, claimed[joinedMessage.ID].MemberName, "outbox messages should have the member name")
+	require.Contains(t, claimed, testMessages[1].ID, "inserted messages should be added to the outbox")
+	assert.Empty(t, claimed[testMessages[1].ID].Cluster)
+	require.Contains(t, claimed, relayed.ID)
+	assert.Equal(t, "us", claimed[relayed.ID].Cluster, "outbox messages should have the cluster they were relayed from")
+	require.Contains(t, claimed, replicatedJoined.ID)
+	assert.Equal(t, "us", claimed[replicatedJoined.ID].Cluster, "replicated members should tag their joined message")
+	require.NoError(t, roomRepo.DeleteRoom(ctx, bridged.ID))
+
+	pending, err = outboxRepo.ClaimPendingMessages(ctx, now, now.Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, pending, claimed[joinedMessage.ID], "claimed messages should be leased")
+
+	err = outboxRepo.MarkFailed(ctx, joinedMessage.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = outboxRepo.ClaimPendingMessages(ctx, now, now.Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, pending, 1, "failed messages should be retried")
+	assert.Equal(t, joinedMessage.ID, pending[0].ID)
+	assert.Equal(t, 1, pending[0].Attempts, "failed attempts should be counted")
+
+	for id := range claimed {
+		if err := outboxRepo.MarkPublished(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err = outboxRepo.ClaimPendingMessages(ctx, now.Add(time.Hour), now.Add(time.Hour), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range pending {
+		assert.NotContains(t, claimed, message.ID, "published messages should be removed from the outbox")
+	}
+
 	deletedMessage, err := memberRepo.DeleteMember(ctx, *member)
 	if err != nil {
 		t.Fatal(err)
@@ -205,4 +458,129 @@ func TestAll(t *testing.T) {
 	}
 
 	assert.False(t, foundMember, "member should no longer be in database")
+
+	inactive, err := roomRepo.GetInactiveRooms(ctx, deletedMessage.Sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, inactive, room.ID, "room with recent messages should not be inactive")
+
+	inactive, err = roomRepo.GetInactiveRooms(ctx, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, inactive, room.ID, "room without recent messages should be inactive")
+
+	retention, err := roomRepo.GetRoomRetention(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, retention.IsEmpty(), "rooms should not override the global retention policy by default")
+
+	_, err = roomRepo.GetRoomRetention(ctx, "missing")
+	assert.ErrorIs(t, err, sharechat.ErrRoomNotFound, "getting the retention of a missing room should fail")
+
+	// only override the policy of this room, since other tests share the database
+	err = roomRepo.SetRoomRetention(ctx, room.ID, sharechat.RetentionPolicy{MaxMessages: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retention, err = roomRepo.GetRoomRetention(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sharechat.RetentionPolicy{MaxMessages: 1}, *retention, "should store the room's retention policy")
+
+	purged, err := roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, purged, "should purge at most a batch of messages")
+	for purged == 1 {
+		if purged, err = roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{}, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, messages, 1, "should only keep the most recent message of the room")
+	assert.Equal(t, deletedMessage.ID, messages[0].ID, "should keep the newest message")
+
+	err = roomRepo.SetRoomRetention(ctx, room.ID, sharechat.RetentionPolicy{MaxAge: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	purged, err = roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, purged, "should purge messages older than the room's max age")
+
+	err = roomRepo.DeleteRoom(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = roomRepo.GetRoom(ctx, room.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "room should be deleted")
+
+	messages, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, messages, "messages should be deleted with the room")
+
+	err = roomRepo.DeleteRoom(ctx, room.ID)
+	assert.ErrorIs(t, err, sharechat.ErrRoomNotFound, "deleting a missing room should fail")
+}
+
+func TestHostileInput(t *testing.T) {
+	db := postgres.NewDB(GetDB())
+	defer db.Close()
+	ctx := context.Background()
+
+	roomRepo := postgres.NewRoomRepository(db)
+	memberRepo := postgres.NewMemberRepository(db)
+	messageRepo := postgres.NewMessageRepository(db)
+
+	hostile := "O'Brien'); DROP TABLE messages; --"
+
+	room := sharechat.NewRoom(hostile)
+	require.NoError(t, roomRepo.InsertRoom(ctx, room), "quotes in room names should not break the query")
+
+	member := sharechat.NewMember(hostile, room.ID, nil)
+	joined, err := memberRepo.InsertMember(ctx, *member)
+	require.NoError(t, err, "quotes in member names should not break the query")
+	assert.Equal(t, "O'Brien'); DROP TABLE messages; -- joined the room.", joined.Message, "member name should be stored verbatim")
+
+	message, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte(hostile)))
+	require.NoError(t, err, "quotes in messages should not break the query")
+	assert.Equal(t, hostile, message.Message, "message should be stored verbatim")
+
+	for _, options := range []sharechat.GetMessageOptions{
+		{RoomID: hostile},
+		{RoomID: room.ID, ThreadID: hostile},
+		{RoomID: room.ID, Before: sharechat.MessageCursor{ID: hostile, Sent: time.Now()}},
+		{RoomID: room.ID, After: sharechat.MessageCursor{ID: hostile, Sent: time.Now().Add(-time.Hour)}},
+	} {
+		_, err := messageRepo.GetMessages(ctx, options)
+		require.NoError(t, err, "quotes in options should not break the query")
+	}
+
+	messages, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, messages, 2, "both messages should be stored")
+	assert.Equal(t, hostile, messages[0].MemberName, "member name should be returned verbatim")
+
+	left, err := memberRepo.DeleteMember(ctx, *member)
+	require.NoError(t, err, "quotes in member names should not break the query")
+	assert.Equal(t, sharechat.MemberLeft, left.Type)
+
+	require.NoError(t, roomRepo.DeleteRoom(ctx, room.ID))
 }
@@ -0,0 +1,357 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
+)
+
+const (
+	NotifyQuery          = "SELECT pg_notify($1, $2)"
+	GetMessageTextQuery  = "SELECT message FROM messages WHERE message_id=$1"
+	notifyChannelPrefix  = "sharechat_room_"
+	notifyPayloadLimit   = 8000 - 1
+	listenerMinReconnect = 10 * time.Millisecond
+	listenerMaxReconnect = time.Minute
+	// subscriptionBuffer is how many notifications may wait for a subscription before it fails
+	subscriptionBuffer = 64
+)
+
+// notification is the payload of a NOTIFY. Payloads are text, so envelopes are always encoded as JSON.
+type notification struct {
+	wire.Envelope
+	// Truncated is set when the message text was dropped to fit in the payload;
+	// subscribers read it from the messages table instead
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+func notifyChannel(roomID string) string {
+	return notifyChannelPrefix + roomID
+}
+
+// encodeNotification encodes message as a NOTIFY payload, dropping its text if
+// the payload would not fit otherwise
+func (q *Queue) encodeNotification(message sharechat.Message) (string, error) {
+	n := notification{Envelope: wire.Envelope{
+		Version:     wire.Version,
+		ContentType: wire.JSON.ContentType(),
+		Origin:      q.origin,
+		Published:   time.Now().UTC(),
+		Message:     message,
+	}}
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) <= notifyPayloadLimit {
+		return string(payload), nil
+	}
+
+	n.Message.Message = ""
+	n.Truncated = true
+	payload, err = json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) > notifyPayloadLimit {
+		return "", fmt.Errorf("message %s does not fit in a notification", message.ID)
+	}
+	return string(payload), nil
+}
+
+// NewQueue creates a Queue that publishes messages with NOTIFY on a channel per room.
+// It opens a dedicated connection with connStr to LISTEN on the channels of subscribed rooms;
+// the connection is re-established automatically if it is lost.
+func NewQueue(db *DB, connStr string) *Queue {
+	q := &Queue{
+		db:       db,
+		channels: make(map[string]*listenChannel),
+		closed:   make(chan struct{}),
+	}
+	q.listener = pq.NewListener(connStr, listenerMinReconnect, listenerMaxReconnect, q.logListenerEvent)
+
+	go q.dispatch()
+	return q
+}
+
+// Queue is a Queue built on Postgres LISTEN/NOTIFY, for deployments that do not run Redis.
+// Like Redis Pub/Sub, messages published while the listening connection is down are not delivered,
+// so every active subscription fails with sharechat.ErrSubscriptionLost once it is re-established.
+type Queue struct {
+	db       *DB
+	listener *pq.Listener
+	origin   string
+
+	mu sync.Mutex
+	// channels maps the channel of a room to its subscriptions
+	channels map[string]*listenChannel
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+type listenChannel struct {
+	// subscriptions is guarded by the Queue's mu
+	subscriptions map[*subscription]struct{}
+
+	// listenMu guards listening & is held while issuing LISTEN or UNLISTEN, which block on
+	// the server; it is never acquired while holding the Queue's mu, so a slow server only
+	// blocks the subscriptions of this channel
+	listenMu  sync.Mutex
+	listening bool
+}
+
+type subscription struct {
+	payloads chan string
+	done     chan struct{}
+	// lost is closed if notifications may have been missed while the subscription was active
+	lost     chan struct{}
+	lostOnce sync.Once
+}
+
+func (s *subscription) fail() {
+	s.lostOnce.Do(func() { close(s.lost) })
+}
+
+func (q *Queue) logListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		log.Printf("lost connection to listen for notifications: %v", err)
+	case pq.ListenerEventReconnected:
+		log.Print("reconnected to listen for notifications")
+	case pq.ListenerEventConnectionAttemptFailed:
+		log.Printf("failed to connect to listen for notifications: %v", err)
+	}
+}
+
+// WithOrigin sets the node named as the origin of published messages
+func (q *Queue) WithOrigin(origin string) *Queue {
+	q.origin = origin
+	return q
+}
+
+// Ping checks the connection used to listen for notifications
+func (q *Queue) Ping(ctx context.Context) error {
+	return q.listener.Ping()
+}
+
+// Close stops listening for notifications; every active subscription fails with sharechat.ErrQueueClosed
+func (q *Queue) Close() error {
+	q.closeOnce.Do(func() { close(q.closed) })
+	return q.listener.Close()
+}
+
+func (q *Queue) Publish(ctx context.Context, message sharechat.Message) error {
+	payload, err := q.encodeNotification(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.db.execContext(ctx, nil, NotifyQuery, notifyChannel(message.RoomID), payload)
+	return err
+}
+
+func (q *Queue) Subscribe(ctx context.Context, roomID string) (sharechat.Subscription, error) {
+	select {
+	case <-q.closed:
+		return nil, sharechat.ErrQueueClosed
+	default:
+	}
+
+	channel := notifyChannel(roomID)
+	sub := &subscription{
+		payloads: make(chan string, subscriptionBuffer),
+		done:     make(chan struct{}),
+		lost:     make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	ch, ok := q.channels[channel]
+	if !ok {
+		ch = &listenChannel{subscriptions: make(map[*subscription]struct{})}
+		q.channels[channel] = ch
+	}
+	ch.subscriptions[sub] = struct{}{}
+	q.mu.Unlock()
+
+	ch.listenMu.Lock()
+	if !ch.listening {
+		// Listen blocks until the server confirms the LISTEN; the channel is still open
+		// if a previous UNLISTEN failed
+		if err := q.listener.Listen(channel); err != nil && !errors.Is(err, pq.ErrChannelAlreadyOpen) {
+			ch.listenMu.Unlock()
+			q.unsubscribe(channel, sub)
+			return nil, err
+		}
+		ch.listening = true
+	}
+	ch.listenMu.Unlock()
+
+	feed := sharechat.NewFeed()
+	go func() {
+		for {
+			select {
+			case payload := <-sub.payloads:
+				message, err := q.decodeNotification(payload)
+				if err != nil {
+					log.Printf("could not decode notification: %v", err)
+					break
+				}
+
+				if !feed.Send(*message) {
+					q.unsubscribe(channel, sub)
+					feed.Stop(nil)
+					return
+				}
+			case <-sub.lost:
+				q.unsubscribe(channel, sub)
+				feed.Stop(sharechat.ErrSubscriptionLost)
+				return
+			case <-q.closed:
+				feed.Stop(sharechat.ErrQueueClosed)
+				return
+			case <-feed.Done():
+				q.unsubscribe(channel, sub)
+				feed.Stop(nil)
+				return
+			}
+		}
+	}()
+
+	return feed, nil
+}
+
+// unsubscribe removes the subscription, no longer listening on the channel once it has no subscriptions left
+func (q *Queue) unsubscribe(name string, sub *subscription) {
+	close(sub.done)
+
+	q.mu.Lock()
+	ch, ok := q.channels[name]
+	if ok {
+		delete(ch.subscriptions, sub)
+	}
+	q.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ch.listenMu.Lock()
+	defer ch.listenMu.Unlock()
+
+	// the channel may have been subscribed to again while waiting to UNLISTEN
+	q.mu.Lock()
+	empty := len(ch.subscriptions) == 0
+	q.mu.Unlock()
+	if !empty {
+		return
+	}
+
+	if ch.listening {
+		if err := q.listener.Unlisten(name); err != nil {
+			log.Printf("failed to stop listening on %s: %v", name, err)
+		}
+		ch.listening = false
+	}
+
+	q.mu.Lock()
+	if len(ch.subscriptions) == 0 && q.channels[name] == ch {
+		delete(q.channels, name)
+	}
+	q.mu.Unlock()
+}
+
+// dispatch forwards notifications to the subscriptions of their room until the Queue is closed
+func (q *Queue) dispatch() {
+	for {
+		select {
+		case n, ok := <-q.listener.Notify:
+			if !ok {
+				return
+			}
+			// a nil notification signals the listener reconnected; anything published
+			// while it was disconnected is lost, so every subscription fails
+			if n == nil {
+				q.mu.Lock()
+				for _, ch := range q.channels {
+					for sub := range ch.subscriptions {
+						sub.fail()
+					}
+				}
+				q.mu.Unlock()
+				continue
+			}
+
+			q.mu.Lock()
+			subs := []*subscription{}
+			if ch, ok := q.channels[n.Channel]; ok {
+				for sub := range ch.subscriptions {
+					subs = append(subs, sub)
+				}
+			}
+			q.mu.Unlock()
+
+			// a subscription that falls behind fails rather than blocking every other room;
+			// the Controller then backfills the messages it missed
+			for _, sub := range subs {
+				select {
+				case sub.payloads <- n.Extra:
+				case <-sub.done:
+				default:
+					sub.fail()
+				}
+			}
+		case <-q.closed:
+			return
+		}
+	}
+}
+
+// decodeNotification decodes a NOTIFY payload, reading the text of truncated messages from the
+// messages table. The text of an edit is the current text of the message it edited.
+func (q *Queue) decodeNotification(payload string) (*sharechat.Message, error) {
+	var version struct {
+		Version int `json:"v"`
+	}
+	if err := json.Unmarshal([]byte(payload), &version); err != nil {
+		return nil, err
+	}
+
+	var n notification
+	if version.Version == 0 {
+		// published before messages were wrapped in an envelope
+		var legacy struct {
+			sharechat.Message
+			Truncated bool `json:"truncated,omitempty"`
+		}
+		if err := json.Unmarshal([]byte(payload), &legacy); err != nil {
+			return nil, err
+		}
+		n.Message, n.Truncated = legacy.Message, legacy.Truncated
+	} else if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return nil, err
+	}
+	if !n.Truncated {
+		return &n.Message, nil
+	}
+
+	messageID := n.Message.ID
+	if n.Message.Type == sharechat.MessageEdited {
+		messageID = n.Message.TargetID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.db.getContext(ctx, nil, &n.Message.Message, GetMessageTextQuery, messageID); err != nil {
+		return nil, fmt.Errorf("failed to read the text of message %s: %w", messageID, err)
+	}
+
+	return &n.Message, nil
+}
@@ -0,0 +1,136 @@
+//go:build int || all
+
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	db := postgres.NewDB(GetDB())
+	defer db.Close()
+	queue := postgres.NewQueue(db, "user=user dbname=public password=password host=0.0.0.0 sslmode=disable")
+	defer queue.Close()
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	require.NoError(t, queue.Ping(ctx))
+
+	roomRepo := postgres.NewRoomRepository(db)
+	memberRepo := postgres.NewMemberRepository(db)
+	messageRepo := postgres.NewMessageRepository(db)
+
+	room := sharechat.NewRoom("test")
+	require.NoError(t, roomRepo.InsertRoom(ctx, room))
+	defer func() { _ = roomRepo.DeleteRoom(context.Background(), room.ID) }()
+
+	member := sharechat.NewMember("test", room.ID, nil)
+	_, err := memberRepo.InsertMember(ctx, *member)
+	require.NoError(t, err)
+
+	subscription, err := queue.Subscribe(ctx, room.ID)
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	receiveMessage := func() sharechat.Message {
+		select {
+		case message := <-subscription.Messages():
+			return message
+		case <-ctx.Done():
+			t.Fatal("message was not delivered")
+			return sharechat.Message{}
+		}
+	}
+
+	published := sharechat.NewChatMessage(*member, []byte("hello world!"))
+	require.NoError(t, queue.Publish(ctx, published))
+
+	message := receiveMessage()
+	assert.Equal(t, published.ID, message.ID, "should deliver the published message")
+	assert.Equal(t, "hello world!", message.Message, "message content should be same")
+
+	// messages too large for a notification are read back from the messages table
+	large := strings.Repeat("a", 10000)
+	stored, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte(large)))
+	require.NoError(t, err)
+	require.NoError(t, queue.Publish(ctx, *stored))
+
+	message = receiveMessage()
+	assert.Equal(t, stored.ID, message.ID, "should deliver the large message")
+	assert.Equal(t, large, message.Message, "should read the text of the large message")
+
+	edited, err := messageRepo.UpdateMessage(ctx, sharechat.NewMessageEditedMessage(*member, stored.ID, []byte(large+"b")))
+	require.NoError(t, err)
+	require.NoError(t, queue.Publish(ctx, sharechat.NewMessageEditedMessage(*member, edited.ID, []byte(large+"b"))))
+
+	message = receiveMessage()
+	assert.Equal(t, sharechat.MessageEdited, message.Type, "should deliver the large edit")
+	assert.Equal(t, large+"b", message.Message, "should read the edited text")
+}
+
+func TestQueueClose(t *testing.T) {
+	db := postgres.NewDB(GetDB())
+	defer db.Close()
+	queue := postgres.NewQueue(db, "user=user dbname=public password=password host=0.0.0.0 sslmode=disable")
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	subscription, err := queue.Subscribe(ctx, sharechat.NewRoom("test").ID)
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Close())
+	_, ok := <-subscription.Messages()
+	assert.False(t, ok, "subscriptions should end once the queue is closed")
+	assert.ErrorIs(t, subscription.Err(), sharechat.ErrQueueClosed, "subscriptions should report the queue closed")
+	require.NoError(t, subscription.Close())
+
+	_, err = queue.Subscribe(ctx, sharechat.NewRoom("test").ID)
+	assert.ErrorIs(t, err, sharechat.ErrQueueClosed, "closed queues should not accept subscriptions")
+}
+
+func TestQueueSlowSubscriber(t *testing.T) {
+	db := postgres.NewDB(GetDB())
+	defer db.Close()
+	queue := postgres.NewQueue(db, "user=user dbname=public password=password host=0.0.0.0 sslmode=disable")
+	defer queue.Close()
+
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	roomA, roomB := sharechat.NewRoom("a").ID, sharechat.NewRoom("b").ID
+	slow, err := queue.Subscribe(ctx, roomA)
+	require.NoError(t, err)
+	defer slow.Close()
+	other, err := queue.Subscribe(ctx, roomB)
+	require.NoError(t, err)
+	defer other.Close()
+
+	// nothing reads the slow subscription, so it falls behind
+	member := sharechat.NewMember("test", roomA, nil)
+	for i := 0; i < 200; i++ {
+		require.NoError(t, queue.Publish(ctx, sharechat.NewChatMessage(*member, []byte("hello world!"))))
+	}
+
+	published := sharechat.NewChatMessage(*sharechat.NewMember("test", roomB, nil), []byte("hello world!"))
+	require.NoError(t, queue.Publish(ctx, published))
+	select {
+	case message := <-other.Messages():
+		assert.Equal(t, published.ID, message.ID, "a slow subscriber should not block other rooms")
+	case <-ctx.Done():
+		t.Fatal("message was not delivered")
+	}
+
+	for range slow.Messages() {
+	}
+	assert.ErrorIs(t, slow.Err(), sharechat.ErrSubscriptionLost, "a slow subscriber should fail so that it backfills")
+}
@@ -4,38 +4,67 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
-	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq" // Postgres Driver
 	"github.com/soggycactus/sharechat.dev/sharechat"
 )
 
 const (
 	InsertRoomQuery = "INSERT INTO rooms (room_id, room_name) VALUES ($1,$2)"
 	GetRoomQuery    = "SELECT room_id, room_name FROM rooms WHERE room_id=$1"
+	DeleteRoomQuery = `
+	WITH deleted_messages AS (DELETE FROM messages WHERE room_id=$1),
+	deleted_members AS (DELETE FROM members WHERE room_id=$1)
+	DELETE FROM rooms WHERE room_id=$1
+	`
+	GetRoomRetentionQuery = "SELECT retention_max_age_ms, retention_max_messages FROM rooms WHERE room_id=$1"
+	SetRoomRetentionQuery = "UPDATE rooms SET retention_max_age_ms=$2, retention_max_messages=$3 WHERE room_id=$1"
+	// PurgeMessagesQuery locks the batch with SKIP LOCKED so concurrent compactions purge different messages;
+	// replies are removed by the cascade on parent_id
+	PurgeMessagesQuery = `
+	DELETE FROM messages WHERE message_id IN (
+		SELECT m.message_id FROM messages m
+		JOIN rooms r ON m.room_id = r.room_id
+		CROSS JOIN LATERAL (
+			SELECT
+				CASE WHEN r.retention_max_age_ms > 0 THEN r.retention_max_age_ms ELSE $1::BIGINT END AS max_age_ms,
+				CASE WHEN r.retention_max_messages > 0 THEN r.retention_max_messages ELSE $2::INTEGER END AS max_messages
+		) p
+		WHERE (p.max_age_ms > 0 AND m.sent < NOW() - p.max_age_ms * INTERVAL '1 millisecond')
+		OR (p.max_messages > 0 AND m.seq <= r.last_seq - p.max_messages)
+		ORDER BY m.sent
+		LIMIT $3
+		FOR UPDATE OF m SKIP LOCKED
+	)
+	`
+	GetInactiveRoomsQuery = `
+	SELECT r.room_id FROM rooms r
+	WHERE r.created_at < $1
+	AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.room_id = r.room_id AND m.sent >= $1)
+	`
 )
 
-func NewRoomRepository(db *sql.DB, driver string) *RoomRepository {
-	return &RoomRepository{db: db, driver: driver}
+func NewRoomRepository(db *DB) *RoomRepository {
+	return &RoomRepository{db: db}
 }
 
 type RoomRepository struct {
-	db     *sql.DB
-	driver string
+	db *DB
 }
 
 func (r *RoomRepository) InsertRoom(ctx context.Context, room *sharechat.Room) error {
-	db := sqlx.NewDb(r.db, r.driver)
-
-	return executeTransaction(ctx, *db, InsertRoomQuery, room.ID, room.Name)
+	_, err := r.db.execContext(ctx, nil, InsertRoomQuery, room.ID, room.Name)
+	return err
 }
 
 func (r *RoomRepository) GetRoom(ctx context.Context, roomID string) (*sharechat.Room, error) {
-	db := sqlx.NewDb(r.db, r.driver)
-
 	var room sharechat.Room
-	err := db.GetContext(ctx, &room, GetRoomQuery, roomID)
+	err := r.db.getContext(ctx, nil, &room, GetRoomQuery, roomID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("room %s does not exist: %w, %w", roomID, sharechat.ErrRoomNotFound, err)
+		}
 		return nil, err
 	}
 
@@ -44,6 +73,84 @@ func (r *RoomRepository) GetRoom(ctx context.Context, roomID string) (*sharechat
 	return &room, nil
 }
 
+// DeleteRoom deletes the room along with all of its members & messages in a single statement.
 func (r *RoomRepository) DeleteRoom(ctx context.Context, roomID string) error {
-	return errors.New("TODO: not implemented")
+	result, err := r.db.execContext(ctx, nil, DeleteRoomQuery, roomID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return sharechat.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+func (r *RoomRepository) GetInactiveRooms(ctx context.Context, cutoff time.Time) ([]string, error) {
+	roomIDs := []string{}
+	if err := r.db.selectContext(ctx, nil, &roomIDs, GetInactiveRoomsQuery, cutoff); err != nil {
+		return nil, err
+	}
+
+	return roomIDs, nil
+}
+
+// roomRetention is how a room's retention policy override is stored
+type roomRetention struct {
+	MaxAgeMS    int64 `db:"retention_max_age_ms"`
+	MaxMessages int   `db:"retention_max_messages"`
+}
+
+func (r *RoomRepository) GetRoomRetention(ctx context.Context, roomID string) (*sharechat.RetentionPolicy, error) {
+	var retention roomRetention
+	err := r.db.getContext(ctx, nil, &retention, GetRoomRetentionQuery, roomID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, sharechat.ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &sharechat.RetentionPolicy{
+		MaxAge:      time.Duration(retention.MaxAgeMS) * time.Millisecond,
+		MaxMessages: retention.MaxMessages,
+	}, nil
+}
+
+func (r *RoomRepository) SetRoomRetention(ctx context.Context, roomID string, policy sharechat.RetentionPolicy) error {
+	result, err := r.db.execContext(ctx, nil, SetRoomRetentionQuery, roomID, policy.MaxAge.Milliseconds(), policy.MaxMessages)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return sharechat.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+func (r *RoomRepository) PurgeMessages(ctx context.Context, policy sharechat.RetentionPolicy, limit int) (int, error) {
+	result, err := r.db.execContext(ctx, nil, PurgeMessagesQuery, policy.MaxAge.Milliseconds(), policy.MaxMessages, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
 }
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+// searchMessagesQuery ranks matches in a subquery so the cursor can compare against the rank;
+// ts_headline is only evaluated for the rows of the returned page.
+var searchMessagesQuery = fmt.Sprintf(`
+	SELECT message_id, type, message, sent, edited_at, room_id, member_id, parent_id, seq, member_name, rank,
+		ts_headline('english', message, query, 'StartSel=%s, StopSel=%s, HighlightAll=false') AS highlight
+	FROM (
+		SELECT m.message_id, m.type, m.message, m.sent, m.edited_at, m.room_id, m.member_id,
+			COALESCE(m.parent_id, '') AS parent_id, m.seq, b.name AS member_name, q.query,
+			ts_rank(m.search, q.query)::FLOAT8 AS rank
+		FROM messages m
+		JOIN members b ON m.member_id = b.member_id
+		CROSS JOIN websearch_to_tsquery('english', $2) AS q(query)
+		WHERE m.room_id=$1 AND m.type='chat' AND m.search @@ q.query
+	) matches
+	WHERE $3::VARCHAR = '' OR (rank, sent, message_id) < ($4::FLOAT8, $5::TIMESTAMPTZ, $3::VARCHAR)
+	ORDER BY rank DESC, sent DESC, message_id DESC
+	LIMIT $6
+	`,
+	sharechat.HighlightStart,
+	sharechat.HighlightStop,
+)
+
+func (m *MessageRepository) SearchMessages(ctx context.Context, options sharechat.SearchMessageOptions) ([]sharechat.SearchResult, error) {
+	result := []sharechat.SearchResult{}
+
+	// default to 100 records per query
+	if options.Limit == 0 {
+		options.Limit = 100
+	}
+
+	if err := m.db.selectContext(
+		ctx,
+		nil,
+		&result,
+		searchMessagesQuery,
+		options.RoomID,
+		options.Query,
+		options.After.ID,
+		options.After.Rank,
+		options.After.Sent,
+		options.Limit,
+	); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -7,33 +7,19 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func executeTransaction(ctx context.Context, db sqlx.DB, query string, args ...interface{}) error {
-	var err error
-	tx, err := db.Beginx()
+// withTransaction runs fn inside a transaction, committing if it succeeds and rolling back otherwise
+func withTransaction(ctx context.Context, db *DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.ExecContext(
-		ctx,
-		query,
-		args...,
-	)
-
-	if err != nil {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
-			err = multierror.Append(err, rollbackErr)
-		}
-		return err
-	}
-
-	err = tx.Commit()
-	if err != nil {
+	if err := fn(tx); err != nil {
 		if rollbackErr := tx.Rollback(); rollbackErr != nil {
 			err = multierror.Append(err, rollbackErr)
 		}
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
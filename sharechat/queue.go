@@ -1,8 +1,101 @@
 package sharechat
 
-import "context"
+import (
+	"context"
+	"sync"
+)
 
 type Queue interface {
 	Publish(ctx context.Context, message Message) error
-	Subscribe(ctx context.Context, roomID string) (func(chan Message, chan struct{}, chan struct{}), error)
+	// Subscribe delivers the messages published to the room after Subscribe returns,
+	// until the Subscription is closed.
+	Subscribe(ctx context.Context, roomID string) (Subscription, error)
+}
+
+// Subscription is a stream of the messages published to a room
+type Subscription interface {
+	// Messages delivers the messages of the room. It is closed once the subscription ends,
+	// either because it was closed or because of the failure reported by Err.
+	Messages() <-chan Message
+	// Close unsubscribes from the room & releases everything the subscription holds.
+	// It is safe to call more than once.
+	Close() error
+	// Err returns the failure that ended the subscription; it is nil while the subscription
+	// is active, or if it was closed before anything failed. A Queue that cannot tell whether
+	// messages were lost while it reconnected ends its subscriptions with ErrSubscriptionLost,
+	// so the Controller can subscribe again & backfill them.
+	Err() error
+}
+
+// NewFeed creates a Feed; Queue implementations use it to implement Subscription.
+func NewFeed() *Feed {
+	return &Feed{
+		messages: make(chan Message),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Feed is a Subscription fed by a single goroutine of a Queue, which sends messages with Send,
+// returns once Done is closed & calls Stop on its way out. Close waits for that goroutine,
+// so a closed Feed holds no goroutines.
+type Feed struct {
+	messages chan Message
+	// done is closed by Close to stop the feeding goroutine
+	done chan struct{}
+	// stopped is closed once the feeding goroutine has stopped
+	stopped   chan struct{}
+	closeOnce sync.Once
+	stopOnce  sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func (f *Feed) Messages() <-chan Message {
+	return f.messages
+}
+
+// Send blocks until the message is received, returning false if the Feed was closed first
+func (f *Feed) Send(message Message) bool {
+	select {
+	case f.messages <- message:
+		return true
+	case <-f.done:
+		return false
+	}
+}
+
+// Done is closed once the Feed has been closed
+func (f *Feed) Done() <-chan struct{} {
+	return f.done
+}
+
+// Stop ends the Feed, closing Messages; err is the failure that ended it, or nil if it was closed.
+// Only the feeding goroutine may call Stop.
+func (f *Feed) Stop(err error) {
+	f.stopOnce.Do(func() {
+		f.mu.Lock()
+		select {
+		case <-f.done:
+		default:
+			f.err = err
+		}
+		f.mu.Unlock()
+
+		close(f.messages)
+		close(f.stopped)
+	})
+}
+
+func (f *Feed) Close() error {
+	f.closeOnce.Do(func() { close(f.done) })
+	<-f.stopped
+	return nil
+}
+
+func (f *Feed) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
 }
@@ -0,0 +1,164 @@
+package sharechat
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// recoveryMaxBackoff caps the delay between attempts to recover a subscription
+	recoveryMaxBackoff = 30 * time.Second
+	// recoveryTimeout bounds each attempt to subscribe again or backfill
+	recoveryTimeout = 5 * time.Second
+	// backfillPageSize is the number of messages read from the repository at once
+	backfillPageSize = 100
+	// backfillLimit is the maximum number of messages backfilled after a subscription is recovered;
+	// members catch up on anything older from the message history
+	backfillLimit = 1000
+)
+
+// recoveryBackoff returns the delay before the next attempt to recover a subscription,
+// doubling with every failed attempt.
+func recoveryBackoff(attempts int) time.Duration {
+	if attempts > 8 {
+		return recoveryMaxBackoff
+	}
+
+	backoff := 100 * time.Millisecond << attempts
+	if backoff > recoveryMaxBackoff {
+		return recoveryMaxBackoff
+	}
+	return backoff
+}
+
+// RecoveringRooms returns the rooms whose subscription to the Queue was lost and is being
+// recovered; they may be missing messages until it is.
+func (c *Controller) RecoveringRooms() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rooms := make([]string, 0, len(c.recovering))
+	for roomID := range c.recovering {
+		rooms = append(rooms, roomID)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+func (c *Controller) setRecovering(roomID string, recovering bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if recovering {
+		c.recovering[roomID] = struct{}{}
+	} else {
+		delete(c.recovering, roomID)
+	}
+}
+
+// lastSeq returns the highest sequence number of the messages of the room, replies included,
+// or 0 if it has none. Messages are ordered by sent time unless selected by sequence number,
+// so the query selects every message below the largest one possible.
+func (c *Controller) lastSeq(ctx context.Context, roomID string) (int64, error) {
+	messages, err := c.messageRepo.GetMessages(ctx, GetMessageOptions{
+		RoomID:         roomID,
+		IncludeReplies: true,
+		BeforeSeq:      math.MaxInt64,
+		Limit:          1,
+	})
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+	return messages[0].Seq, nil
+}
+
+// retry calls fn with backoff until it succeeds, fails with ErrQueueClosed or the room stops.
+// It reports whether fn succeeded.
+func (c *Controller) retry(room *Room, action string, fn func(ctx context.Context) error) bool {
+	for attempts := 0; ; attempts++ {
+		ctx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+		err := fn(ctx)
+		cancel()
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, ErrQueueClosed) {
+			log.Printf("failed to %s for room %s: %v", action, room.ID, err)
+			return false
+		}
+
+		backoff := recoveryBackoff(attempts)
+		log.Printf("failed to %s for room %s (attempt %d), retrying in %s: %v", action, room.ID, attempts+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-room.shutdown:
+			return false
+		}
+	}
+}
+
+// recoverSubscription subscribes to the room again after its subscription failed, then reads
+// the messages stored after lastSeq, which may have been published while it was down. It returns
+// a nil Subscription if the Queue was closed or the room stopped before it recovered.
+func (c *Controller) recoverSubscription(room *Room, lastSeq int64) (Subscription, []Message) {
+	c.setRecovering(room.ID, true)
+	defer c.setRecovering(room.ID, false)
+
+	var subscription Subscription
+	subscribed := c.retry(room, "subscribe", func(ctx context.Context) (err error) {
+		subscription, err = c.queue.Subscribe(ctx, room.ID)
+		return err
+	})
+	if !subscribed {
+		return nil, nil
+	}
+
+	var backfill []Message
+	backfilled := c.retry(room, "backfill messages", func(ctx context.Context) error {
+		if lastSeq == 0 {
+			// the room had no messages, and sequence numbers cannot select from the first one;
+			// read the newest messages instead, which are returned newest first
+			messages, err := c.messageRepo.GetMessages(ctx, GetMessageOptions{
+				RoomID:         room.ID,
+				IncludeReplies: true,
+				Limit:          backfillLimit,
+			})
+			if err != nil {
+				return err
+			}
+			for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+				messages[i], messages[j] = messages[j], messages[i]
+			}
+			backfill = messages
+			return nil
+		}
+
+		backfill = []Message{}
+		for after := lastSeq; len(backfill) < backfillLimit; {
+			messages, err := c.messageRepo.GetMessages(ctx, GetMessageOptions{
+				RoomID:         room.ID,
+				IncludeReplies: true,
+				AfterSeq:       after,
+				Limit:          backfillPageSize,
+			})
+			if err != nil {
+				return err
+			}
+			backfill = append(backfill, messages...)
+			if len(messages) < backfillPageSize {
+				break
+			}
+			after = messages[len(messages)-1].Seq
+		}
+		return nil
+	})
+	if !backfilled {
+		subscription.Close()
+		return nil, nil
+	}
+
+	return subscription, backfill
+}
@@ -2,19 +2,70 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
 	"log"
+	"sync"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
+)
+
+const (
+	// pubsubBuffer is how many payloads the Pub/Sub connection buffers before they are demultiplexed
+	pubsubBuffer = 100
+	// subscriberBuffer is how many messages may wait for a subscriber before it fails
+	subscriberBuffer = 64
 )
 
 func NewQueue(client redis.Client) *Queue {
-	return &Queue{redisClient: &client}
+	return &Queue{
+		redisClient: &client,
+		encoder:     wire.NewEncoder(wire.JSON, ""),
+		topics:      make(map[string]*topic),
+	}
 }
 
+// Queue is a Queue built on Redis Pub/Sub. Every room is subscribed to over a single Pub/Sub
+// connection, which is opened by the first subscription. The connection is health checked,
+// and if it is lost go-redis reconnects & subscribes to every active room again; their
+// subscriptions then fail with sharechat.ErrSubscriptionLost, as messages may have been missed.
 type Queue struct {
 	redisClient *redis.Client
+	encoder     *wire.Encoder
+
+	mu     sync.Mutex
+	pubsub *redis.PubSub
+	// topics maps a room to its subscribers
+	topics map[string]*topic
+}
+
+type topic struct {
+	subscribers map[*subscriber]struct{}
+	// confirmed is closed once Redis confirms the subscription to the room
+	confirmed chan struct{}
+}
+
+type subscriber struct {
+	messages chan sharechat.Message
+	// done is closed once the subscriber has unsubscribed
+	done chan struct{}
+	// failed is closed with err set if messages may have been lost while the subscriber was subscribed
+	failed   chan struct{}
+	failOnce sync.Once
+	err      error
+}
+
+func (s *subscriber) fail(err error) {
+	s.failOnce.Do(func() {
+		s.err = err
+		close(s.failed)
+	})
+}
+
+// WithEncoder sets how published messages are encoded; messages are decoded whatever their codec
+func (q *Queue) WithEncoder(encoder *wire.Encoder) *Queue {
+	q.encoder = encoder
+	return q
 }
 
 func (q *Queue) Ping(ctx context.Context) error {
@@ -22,32 +73,184 @@ func (q *Queue) Ping(ctx context.Context) error {
 }
 
 func (q *Queue) Publish(ctx context.Context, message sharechat.Message) error {
-	return q.redisClient.Publish(ctx, message.RoomID, message).Err()
+	payload, err := q.encoder.Encode(message)
+	if err != nil {
+		return err
+	}
+	return q.redisClient.Publish(ctx, message.RoomID, payload).Err()
 }
 
-func (q *Queue) Subscribe(ctx context.Context, roomID string) (func(chan sharechat.Message, chan struct{}, chan struct{}), error) {
-	topic := q.redisClient.Subscribe(ctx, roomID)
-	// Receive forces us to wait on a response from Redis
-	_, err := topic.Receive(ctx)
-	if err != nil {
-		return nil, err
+func (q *Queue) Subscribe(ctx context.Context, roomID string) (sharechat.Subscription, error) {
+	sub := &subscriber{
+		messages: make(chan sharechat.Message, subscriberBuffer),
+		done:     make(chan struct{}),
+		failed:   make(chan struct{}),
 	}
-	channel := topic.Channel()
 
-	return func(controller chan sharechat.Message, done, ready chan struct{}) {
-		ready <- struct{}{}
+	q.mu.Lock()
+	t, ok := q.topics[roomID]
+	var pubsub *redis.PubSub
+	if !ok {
+		t = &topic{
+			subscribers: make(map[*subscriber]struct{}),
+			confirmed:   make(chan struct{}),
+		}
+		q.topics[roomID] = t
+
+		if q.pubsub == nil {
+			q.pubsub = q.redisClient.Subscribe(context.Background())
+			go q.demultiplex(q.pubsub, q.pubsub.ChannelWithSubscriptions(context.Background(), pubsubBuffer))
+		}
+		pubsub = q.pubsub
+	}
+	t.subscribers[sub] = struct{}{}
+	q.mu.Unlock()
+
+	// the first subscriber of a room subscribes to it without holding the lock, so a slow
+	// Redis does not block every other room; later subscribers wait on the confirmation
+	if pubsub != nil {
+		if err := pubsub.Subscribe(ctx, roomID); err != nil {
+			q.mu.Lock()
+			for sub := range t.subscribers {
+				sub.fail(err)
+			}
+			q.mu.Unlock()
+		}
+	}
+
+	// wait on Redis to confirm the subscription, so messages published after Subscribe returns are delivered
+	select {
+	case <-t.confirmed:
+	case <-sub.failed:
+		q.unsubscribe(roomID, sub)
+		return nil, sub.err
+	case <-ctx.Done():
+		q.unsubscribe(roomID, sub)
+		return nil, ctx.Err()
+	}
+
+	feed := sharechat.NewFeed()
+	go func() {
 		for {
 			select {
-			case msg := <-channel:
-				var message *sharechat.Message
-				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
-					log.Printf("could not unmarshal message: %v", err)
-					break
+			case message := <-sub.messages:
+				if !feed.Send(message) {
+					q.unsubscribe(roomID, sub)
+					feed.Stop(nil)
+					return
 				}
-				controller <- *message
-			case <-done:
+			case <-sub.failed:
+				q.unsubscribe(roomID, sub)
+				feed.Stop(sub.err)
+				return
+			case <-feed.Done():
+				q.unsubscribe(roomID, sub)
+				feed.Stop(nil)
 				return
 			}
 		}
-	}, nil
+	}()
+
+	return feed, nil
+}
+
+// unsubscribe removes the subscriber, unsubscribing from the room once it has no subscribers left.
+// The Pub/Sub connection is closed once no room is subscribed to.
+func (q *Queue) unsubscribe(roomID string, sub *subscriber) {
+	close(sub.done)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.topics[roomID]
+	if !ok {
+		return
+	}
+
+	delete(t.subscribers, sub)
+	if len(t.subscribers) != 0 {
+		return
+	}
+
+	delete(q.topics, roomID)
+	if len(q.topics) == 0 {
+		if err := q.pubsub.Close(); err != nil {
+			log.Printf("failed to close pub/sub connection: %v", err)
+		}
+		q.pubsub = nil
+		return
+	}
+
+	if err := q.pubsub.Unsubscribe(context.Background(), roomID); err != nil {
+		log.Printf("failed to unsubscribe from room %s: %v", roomID, err)
+	}
+}
+
+// demultiplex forwards the payloads of the Pub/Sub connection to the subscribers of their room.
+// If the connection is closed while rooms are still subscribed to, their subscribers fail.
+// go-redis subscribes to every room again after reconnecting, and since anything published while
+// the connection was down is lost, the subscribers of a room confirmed again fail as well.
+func (q *Queue) demultiplex(pubsub *redis.PubSub, payloads <-chan interface{}) {
+	for payload := range payloads {
+		switch payload := payload.(type) {
+		case *redis.Subscription:
+			if payload.Kind != "subscribe" {
+				continue
+			}
+
+			q.mu.Lock()
+			if t, ok := q.topics[payload.Channel]; ok {
+				select {
+				case <-t.confirmed:
+					for sub := range t.subscribers {
+						sub.fail(sharechat.ErrSubscriptionLost)
+					}
+				default:
+					close(t.confirmed)
+				}
+			}
+			q.mu.Unlock()
+		case *redis.Message:
+			envelope, err := wire.Decode([]byte(payload.Payload))
+			if err != nil {
+				log.Printf("could not decode message: %v", err)
+				continue
+			}
+			message := envelope.Message
+
+			q.mu.Lock()
+			subscribers := []*subscriber{}
+			if t, ok := q.topics[payload.Channel]; ok {
+				for sub := range t.subscribers {
+					subscribers = append(subscribers, sub)
+				}
+			}
+			q.mu.Unlock()
+
+			// a subscriber that falls behind fails rather than blocking every other room;
+			// the Controller then backfills the messages it missed
+			for _, sub := range subscribers {
+				select {
+				case sub.messages <- message:
+				case <-sub.done:
+				default:
+					sub.fail(sharechat.ErrSubscriptionLost)
+				}
+			}
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pubsub != pubsub {
+		// closed once the last room was unsubscribed from
+		return
+	}
+
+	for _, t := range q.topics {
+		for sub := range t.subscribers {
+			sub.fail(sharechat.ErrQueueClosed)
+		}
+	}
+	q.topics = make(map[string]*topic)
+	q.pubsub = nil
 }
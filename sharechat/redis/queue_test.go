@@ -11,6 +11,7 @@ import (
 	"github.com/soggycactus/sharechat.dev/sharechat"
 	"github.com/soggycactus/sharechat.dev/sharechat/redis"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestQueue(t *testing.T) {
@@ -28,16 +29,11 @@ func TestQueue(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	controller := make(chan sharechat.Message)
-	done := make(chan struct{})
-	ready := make(chan struct{})
-	receive, err := queue.Subscribe(ctx, "test")
+	subscription, err := queue.Subscribe(ctx, "test")
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	go receive(controller, done, ready)
-	<-ready
+	defer subscription.Close()
 
 	member := sharechat.NewMember("test", "test", nil)
 	err = queue.Publish(ctx, sharechat.NewChatMessage(*member, []byte("hello world!")))
@@ -45,7 +41,120 @@ func TestQueue(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	message := <-controller
+	message := <-subscription.Messages()
 	assert.Equal(t, member.ID, message.MemberID, "message should have correct Member")
 	assert.Equal(t, "hello world!", message.Message, "message content should be same")
 }
+
+func TestQueueMultiplexing(t *testing.T) {
+	client := redisv8.NewClient(&redisv8.Options{
+		Addr:     "0.0.0.0:6379",
+		Username: "",
+		Password: "",
+	})
+	queue := redis.NewQueue(*client)
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	roomA, roomB := sharechat.NewRoom("a").ID, sharechat.NewRoom("b").ID
+	subscribe := func(roomID string) sharechat.Subscription {
+		subscription, err := queue.Subscribe(ctx, roomID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return subscription
+	}
+
+	first := subscribe(roomA)
+	second := subscribe(roomA)
+	other := subscribe(roomB)
+
+	subscriptions, err := client.PubSubNumSub(ctx, roomA, roomB).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), subscriptions[roomA], "rooms should be subscribed to once, however many subscribers they have")
+	assert.Equal(t, int64(1), subscriptions[roomB])
+
+	member := sharechat.NewMember("test", roomA, nil)
+	published := sharechat.NewChatMessage(*member, []byte("hello world!"))
+	if err := queue.Publish(ctx, published); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subscription := range []sharechat.Subscription{first, second} {
+		select {
+		case message := <-subscription.Messages():
+			assert.Equal(t, published.ID, message.ID, "every subscriber of the room should receive the message")
+		case <-ctx.Done():
+			t.Fatal("message was not delivered")
+		}
+	}
+
+	select {
+	case message := <-other.Messages():
+		t.Fatalf("subscribers of other rooms should not receive message %s", message.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, first.Close())
+	published = sharechat.NewChatMessage(*member, []byte("still here"))
+	if err := queue.Publish(ctx, published); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case message := <-second.Messages():
+		assert.Equal(t, published.ID, message.ID, "room should stay subscribed while it has subscribers")
+	case <-ctx.Done():
+		t.Fatal("message was not delivered")
+	}
+
+	// once every subscription is closed, every room is unsubscribed from
+	require.NoError(t, second.Close())
+	require.NoError(t, other.Close())
+	subscriptions, err = client.PubSubNumSub(ctx, roomA, roomB).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, subscriptions[roomA], "closed subscriptions should unsubscribe")
+	assert.Zero(t, subscriptions[roomB], "closed subscriptions should unsubscribe")
+}
+
+func TestQueueSlowSubscriber(t *testing.T) {
+	client := redisv8.NewClient(&redisv8.Options{
+		Addr:     "0.0.0.0:6379",
+		Username: "",
+		Password: "",
+	})
+	queue := redis.NewQueue(*client)
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	roomA, roomB := sharechat.NewRoom("a").ID, sharechat.NewRoom("b").ID
+	slow, err := queue.Subscribe(ctx, roomA)
+	require.NoError(t, err)
+	defer slow.Close()
+	other, err := queue.Subscribe(ctx, roomB)
+	require.NoError(t, err)
+	defer other.Close()
+
+	// nothing reads the slow subscription, so it falls behind
+	member := sharechat.NewMember("test", roomA, nil)
+	for i := 0; i < 200; i++ {
+		require.NoError(t, queue.Publish(ctx, sharechat.NewChatMessage(*member, []byte("hello world!"))))
+	}
+
+	published := sharechat.NewChatMessage(*sharechat.NewMember("test", roomB, nil), []byte("hello world!"))
+	require.NoError(t, queue.Publish(ctx, published))
+	select {
+	case message := <-other.Messages():
+		assert.Equal(t, published.ID, message.ID, "a slow subscriber should not block other rooms")
+	case <-ctx.Done():
+		t.Fatal("message was not delivered")
+	}
+
+	for range slow.Messages() {
+	}
+	assert.ErrorIs(t, slow.Err(), sharechat.ErrSubscriptionLost, "a slow subscriber should fail so that it backfills")
+}
@@ -0,0 +1,409 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
+)
+
+const (
+	// streamPrefix namespaces the stream of each room
+	streamPrefix = "sharechat:room:"
+	// wakePrefix namespaces the stream each StreamQueue appends to when it subscribes to a new room,
+	// which its reader also reads so it stops blocking & starts reading the new room
+	wakePrefix = "sharechat:wake:"
+	// streamField is the field of a stream entry holding the message
+	streamField = "message"
+	// streamReadCount is the maximum number of entries read at once from each stream
+	streamReadCount = 100
+	// streamBlock is how long a read waits for new entries before trying again
+	streamBlock = 5 * time.Second
+	// streamTTL expires the stream of a room once nothing has been published to it for a day,
+	// so the streams of deleted rooms do not linger; the messages themselves are kept by the repository
+	streamTTL = 24 * time.Hour
+	// streamMaxBackoff caps the delay between attempts to read the streams after an error
+	streamMaxBackoff = 10 * time.Second
+)
+
+// NewStreamQueue creates a Queue that appends the messages of each room to a Redis Stream.
+// Streams are trimmed to roughly maxLen entries; a maxLen of 0 leaves them untrimmed.
+func NewStreamQueue(client redis.Client, maxLen int64) *StreamQueue {
+	// the reader blocks its connection, so it gets a client of its own rather than one from the pool
+	options := *client.Options()
+	options.PoolSize = 1
+	options.MinIdleConns = 0
+
+	return &StreamQueue{
+		redisClient: &client,
+		readClient:  redis.NewClient(&options),
+		maxLen:      maxLen,
+		encoder:     wire.NewEncoder(wire.JSON, ""),
+		wakeKey:     wakePrefix + uuid.New().String(),
+		wakeID:      "0-0",
+		streams:     make(map[string]*stream),
+	}
+}
+
+// StreamQueue is a Queue built on Redis Streams. Unlike Pub/Sub, entries outlive the connection
+// that published them, so when the reader loses its connection it resumes reading every room after
+// the last entry it delivered instead of missing everything published in the meantime. Every room
+// is read by a single reader, which is started by the first subscription & blocks on one XREAD of
+// all the subscribed streams. If a stream is trimmed past the last entry delivered, entries may have
+// been lost, and the subscriptions of the room fail with sharechat.ErrSubscriptionLost.
+type StreamQueue struct {
+	redisClient *redis.Client
+	readClient  *redis.Client
+	maxLen      int64
+	encoder     *wire.Encoder
+
+	// wakeKey is the stream appended to when a room is subscribed to, which the reader last read at wakeID
+	wakeKey string
+	wakeID  string
+
+	mu sync.Mutex
+	// streams maps the stream of a room to its subscribers
+	streams map[string]*stream
+	// reading is whether the reader is running
+	reading bool
+}
+
+type stream struct {
+	// lastID is the entry after which the stream is read
+	lastID string
+	// seen is whether lastID is an entry of the stream, so trimming past it may have lost entries
+	seen        bool
+	subscribers map[*subscriber]struct{}
+}
+
+func streamKey(roomID string) string {
+	return streamPrefix + roomID
+}
+
+// WithEncoder sets how appended messages are encoded; entries are decoded whatever their codec
+func (q *StreamQueue) WithEncoder(encoder *wire.Encoder) *StreamQueue {
+	q.encoder = encoder
+	return q
+}
+
+func (q *StreamQueue) Ping(ctx context.Context) error {
+	return q.redisClient.Ping(ctx).Err()
+}
+
+func (q *StreamQueue) Publish(ctx context.Context, message sharechat.Message) error {
+	payload, err := q.encoder.Encode(message)
+	if err != nil {
+		return err
+	}
+
+	key := streamKey(message.RoomID)
+	_, err = q.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			MaxLen: q.maxLen,
+			// exact trimming is expensive; letting the stream run slightly over maxLen is not
+			Approx: true,
+			Values: map[string]interface{}{streamField: payload},
+		})
+		pipe.Expire(ctx, key, streamTTL)
+		return nil
+	})
+	return err
+}
+
+// Subscribe delivers the messages published to the room after Subscribe returns
+func (q *StreamQueue) Subscribe(ctx context.Context, roomID string) (sharechat.Subscription, error) {
+	key := streamKey(roomID)
+
+	// start after the newest entry rather than at "$", which would skip
+	// anything published between reads
+	lastID, seen := "0-0", false
+	entries, err := q.redisClient.XRevRangeN(ctx, key, "+", "-", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 0 {
+		lastID, seen = entries[0].ID, true
+	}
+
+	sub := &subscriber{
+		messages: make(chan sharechat.Message, subscriberBuffer),
+		done:     make(chan struct{}),
+		failed:   make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	s, ok := q.streams[key]
+	if !ok {
+		s = &stream{lastID: lastID, seen: seen, subscribers: make(map[*subscriber]struct{})}
+		q.streams[key] = s
+	}
+	s.subscribers[sub] = struct{}{}
+	start := !q.reading
+	q.reading = true
+	q.mu.Unlock()
+
+	switch {
+	case start:
+		go q.read()
+	case !ok:
+		// the reader is blocked on the streams it read before; if it cannot be woken
+		// it reads the new room once the block expires
+		if err := q.wake(ctx); err != nil {
+			log.Printf("failed to wake the reader of stream %s: %v", key, err)
+		}
+	}
+
+	feed := sharechat.NewFeed()
+	go func() {
+		for {
+			select {
+			case message := <-sub.messages:
+				if !feed.Send(message) {
+					q.unsubscribe(key, sub)
+					feed.Stop(nil)
+					return
+				}
+			case <-sub.failed:
+				q.unsubscribe(key, sub)
+				feed.Stop(sub.err)
+				return
+			case <-feed.Done():
+				q.unsubscribe(key, sub)
+				feed.Stop(nil)
+				return
+			}
+		}
+	}()
+
+	return feed, nil
+}
+
+// wake appends to the wake stream, so the reader stops blocking & reads the streams subscribed to since
+func (q *StreamQueue) wake(ctx context.Context) error {
+	_, err := q.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.wakeKey,
+			MaxLen: 1,
+			Values: map[string]interface{}{streamField: ""},
+		})
+		pipe.Expire(ctx, q.wakeKey, streamTTL)
+		return nil
+	})
+	return err
+}
+
+// unsubscribe removes the subscriber, no longer reading the stream once it has no subscribers left
+func (q *StreamQueue) unsubscribe(key string, sub *subscriber) {
+	close(sub.done)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s, ok := q.streams[key]
+	if !ok {
+		return
+	}
+
+	delete(s.subscribers, sub)
+	if len(s.subscribers) == 0 {
+		delete(q.streams, key)
+	}
+}
+
+// read reads every subscribed stream until none are left, delivering their entries to the subscribers.
+// Failed reads are retried with exponential backoff from the last entry delivered.
+func (q *StreamQueue) read() {
+	ctx := context.Background()
+	attempts := 0
+	for {
+		q.mu.Lock()
+		if len(q.streams) == 0 {
+			q.reading = false
+			q.mu.Unlock()
+			return
+		}
+
+		keys := []string{q.wakeKey}
+		ids := []string{q.wakeID}
+		seen := make(map[string]string)
+		for key, s := range q.streams {
+			keys = append(keys, key)
+			ids = append(ids, s.lastID)
+			if s.seen {
+				seen[key] = s.lastID
+			}
+		}
+		q.mu.Unlock()
+
+		var streams []redis.XStream
+		trimmed, err := q.trimmed(ctx, seen)
+		if err == nil {
+			q.fail(trimmed, sharechat.ErrSubscriptionLost)
+			streams, err = q.readClient.XRead(ctx, &redis.XReadArgs{
+				Streams: append(keys, ids...),
+				Count:   streamReadCount,
+				Block:   streamBlock,
+			}).Result()
+		}
+
+		switch {
+		case errors.Is(err, redis.Nil):
+			// no new entries before the block expired
+			attempts = 0
+			continue
+		case err != nil:
+			backoff := streamBackoff(attempts)
+			attempts++
+			log.Printf("failed to read %d streams, retrying in %s: %v", len(keys)-1, backoff, err)
+			time.Sleep(backoff)
+			continue
+		}
+		attempts = 0
+
+		for _, stream := range streams {
+			if stream.Stream == q.wakeKey {
+				if len(stream.Messages) != 0 {
+					q.wakeID = stream.Messages[len(stream.Messages)-1].ID
+				}
+				continue
+			}
+
+			for _, entry := range stream.Messages {
+				q.deliver(stream.Stream, entry)
+			}
+		}
+	}
+}
+
+// trimmed returns the streams whose oldest entry is newer than the last entry delivered,
+// since anything published in between has been trimmed
+func (q *StreamQueue) trimmed(ctx context.Context, lastIDs map[string]string) ([]string, error) {
+	if len(lastIDs) == 0 {
+		return nil, nil
+	}
+
+	oldest := make(map[string]*redis.XMessageSliceCmd, len(lastIDs))
+	_, err := q.readClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key := range lastIDs {
+			oldest[key] = pipe.XRangeN(ctx, key, "-", "+", 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := []string{}
+	for key, cmd := range oldest {
+		entries := cmd.Val()
+		if len(entries) != 0 && compareStreamIDs(entries[0].ID, lastIDs[key]) > 0 {
+			trimmed = append(trimmed, key)
+		}
+	}
+	return trimmed, nil
+}
+
+// fail fails every subscriber of the streams, which are no longer read
+func (q *StreamQueue) fail(keys []string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, key := range keys {
+		s, ok := q.streams[key]
+		if !ok {
+			continue
+		}
+
+		log.Printf("stream %s was trimmed past %s, entries may have been lost", key, s.lastID)
+		for sub := range s.subscribers {
+			sub.fail(err)
+		}
+		delete(q.streams, key)
+	}
+}
+
+// deliver sends a stream entry to the subscribers of its stream
+func (q *StreamQueue) deliver(key string, entry redis.XMessage) {
+	// entries that cannot be delivered are still read past
+	payload, ok := entry.Values[streamField].(string)
+	if !ok {
+		log.Printf("stream entry %s has no message", entry.ID)
+	}
+
+	q.mu.Lock()
+	s, subscribed := q.streams[key]
+	// the stream may have been subscribed to again after a newer entry while it was read
+	if !subscribed || compareStreamIDs(entry.ID, s.lastID) <= 0 {
+		q.mu.Unlock()
+		return
+	}
+	s.lastID, s.seen = entry.ID, true
+	subscribers := []*subscriber{}
+	for sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	q.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	envelope, err := wire.Decode([]byte(payload))
+	if err != nil {
+		log.Printf("could not decode stream entry %s: %v", entry.ID, err)
+		return
+	}
+
+	// a subscriber that falls behind fails rather than blocking every other room;
+	// the Controller then backfills the messages it missed
+	for _, sub := range subscribers {
+		select {
+		case sub.messages <- envelope.Message:
+		case <-sub.done:
+		default:
+			sub.fail(sharechat.ErrSubscriptionLost)
+		}
+	}
+}
+
+// compareStreamIDs returns -1, 0 or 1 as the stream entry ID a is before, equal to or after b
+func compareStreamIDs(a, b string) int {
+	aMillis, aSeq := parseStreamID(a)
+	bMillis, bSeq := parseStreamID(b)
+	switch {
+	case aMillis < bMillis, aMillis == bMillis && aSeq < bSeq:
+		return -1
+	case aMillis == bMillis && aSeq == bSeq:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// parseStreamID splits a stream entry ID into its milliseconds & sequence number
+func parseStreamID(id string) (uint64, uint64) {
+	millis, seq, _ := strings.Cut(id, "-")
+	m, _ := strconv.ParseUint(millis, 10, 64)
+	s, _ := strconv.ParseUint(seq, 10, 64)
+	return m, s
+}
+
+// streamBackoff returns the delay before reading the streams again after a failed read,
+// doubling with every consecutive failure.
+func streamBackoff(attempts int) time.Duration {
+	if attempts > 8 {
+		return streamMaxBackoff
+	}
+
+	backoff := 100 * time.Millisecond << attempts
+	if backoff > streamMaxBackoff {
+		return streamMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,121 @@
+//go:build int || all
+
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	redisv8 "github.com/go-redis/redis/v8"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamQueue(t *testing.T) {
+	client := redisv8.NewClient(&redisv8.Options{
+		Addr:     "0.0.0.0:6379",
+		Username: "",
+		Password: "",
+	})
+	queue := redis.NewStreamQueue(*client, 10)
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	err := queue.Ping(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	member := sharechat.NewMember("test", sharechat.NewRoom("test").ID, nil)
+	before := sharechat.NewChatMessage(*member, []byte("before"))
+	require.NoError(t, queue.Publish(ctx, before))
+
+	subscription, err := queue.Subscribe(ctx, member.RoomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// messages published before the subscription starts reading are still delivered
+	published := []sharechat.Message{}
+	for i := 0; i < 3; i++ {
+		message := sharechat.NewChatMessage(*member, []byte("hello world!"))
+		require.NoError(t, queue.Publish(ctx, message))
+		published = append(published, message)
+	}
+
+	for _, expected := range published {
+		select {
+		case message := <-subscription.Messages():
+			assert.Equal(t, expected.ID, message.ID, "messages should be delivered in order")
+			assert.Equal(t, "hello world!", message.Message, "message content should be same")
+		case <-ctx.Done():
+			t.Fatal("message was not delivered")
+		}
+	}
+
+	require.NoError(t, subscription.Close())
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, queue.Publish(ctx, sharechat.NewChatMessage(*member, []byte("trimmed"))))
+	}
+	length, err := client.XLen(ctx, "sharechat:room:"+member.RoomID).Result()
+	require.NoError(t, err)
+	assert.Less(t, length, int64(24), "stream should be trimmed")
+}
+
+func TestStreamQueueReconnect(t *testing.T) {
+	client := redisv8.NewClient(&redisv8.Options{
+		Addr:     "0.0.0.0:6379",
+		Username: "",
+		Password: "",
+	})
+	queue := redis.NewStreamQueue(*client, 0)
+	ctx, fn := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer fn()
+
+	// rooms are read over a single connection
+	rooms := []*sharechat.Member{
+		sharechat.NewMember("first", sharechat.NewRoom("first").ID, nil),
+		sharechat.NewMember("second", sharechat.NewRoom("second").ID, nil),
+	}
+	subscriptions := []sharechat.Subscription{}
+	for _, member := range rooms {
+		subscription, err := queue.Subscribe(ctx, member.RoomID)
+		require.NoError(t, err)
+		defer subscription.Close()
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	receive := func(subscription sharechat.Subscription, expected sharechat.Message) {
+		select {
+		case message := <-subscription.Messages():
+			assert.Equal(t, expected.ID, message.ID, "messages should be delivered once & in order")
+		case <-ctx.Done():
+			t.Fatalf("message %s was not delivered", expected.ID)
+		}
+	}
+
+	for i, member := range rooms {
+		message := sharechat.NewChatMessage(*member, []byte("before"))
+		require.NoError(t, queue.Publish(ctx, message))
+		receive(subscriptions[i], message)
+	}
+
+	// kill every other connection, including the reader's, which resumes after the last entry it delivered
+	require.NoError(t, client.ClientKillByFilter(ctx, "TYPE", "normal").Err())
+
+	for i, member := range rooms {
+		published := []sharechat.Message{}
+		for j := 0; j < 3; j++ {
+			message := sharechat.NewChatMessage(*member, []byte("after"))
+			require.NoError(t, queue.Publish(ctx, message))
+			published = append(published, message)
+		}
+		for _, message := range published {
+			receive(subscriptions[i], message)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package sharechat
+
+import (
+	"errors"
+	"expvar"
+	"time"
+)
+
+// RetentionPolicy limits how long messages are kept. A zero value for either limit means no limit.
+type RetentionPolicy struct {
+	// MaxAge is how long a message is kept after it was sent; repositories store it in milliseconds
+	MaxAge time.Duration
+	// MaxMessages is how many of the most recent messages of a room are kept
+	MaxMessages int
+}
+
+func (r RetentionPolicy) IsEmpty() bool {
+	return r.MaxAge == 0 && r.MaxMessages == 0
+}
+
+func (r RetentionPolicy) Validate() error {
+	if r.MaxAge < 0 {
+		return errors.Join(ErrInvalidOptions, errors.New("max age must not be negative"))
+	}
+
+	if r.MaxMessages < 0 {
+		return errors.Join(ErrInvalidOptions, errors.New("max messages must not be negative"))
+	}
+
+	return nil
+}
+
+// Override returns the policy with every limit set by override replacing the one of r
+func (r RetentionPolicy) Override(override RetentionPolicy) RetentionPolicy {
+	if override.MaxAge != 0 {
+		r.MaxAge = override.MaxAge
+	}
+	if override.MaxMessages != 0 {
+		r.MaxMessages = override.MaxMessages
+	}
+	return r
+}
+
+const (
+	// compactionBatchSize is the maximum number of messages purged per statement,
+	// keeping each delete short so it does not hold locks for long
+	compactionBatchSize = 1000
+)
+
+// Compaction metrics, exposed with the rest of expvar at /debug/vars
+var (
+	compactionRuns   = expvar.NewInt("compaction_runs")
+	compactionErrors = expvar.NewInt("compaction_errors")
+	purgedMessages   = expvar.NewInt("compaction_purged_messages")
+)
@@ -12,7 +12,21 @@ import (
 type RoomRepository interface {
 	InsertRoom(ctx context.Context, room *Room) error
 	GetRoom(ctx context.Context, roomID string) (*Room, error)
+	// DeleteRoom deletes the room along with all of its members & messages.
+	// ErrRoomNotFound is returned if the room does not exist.
 	DeleteRoom(ctx context.Context, roomID string) error
+	// GetInactiveRooms returns the IDs of rooms created before cutoff that have no messages sent since cutoff.
+	GetInactiveRooms(ctx context.Context, cutoff time.Time) ([]string, error)
+	// GetRoomRetention returns the retention policy override stored with the room;
+	// limits the room does not override are zero. ErrRoomNotFound is returned if the room does not exist.
+	GetRoomRetention(ctx context.Context, roomID string) (*RetentionPolicy, error)
+	// SetRoomRetention stores a retention policy override with the room.
+	// ErrRoomNotFound is returned if the room does not exist.
+	SetRoomRetention(ctx context.Context, roomID string, policy RetentionPolicy) error
+	// PurgeMessages deletes up to limit messages exceeding the retention policy of their room,
+	// which is policy overridden by the room's own, returning how many it deleted.
+	// Replies are deleted along with their thread, but are not counted.
+	PurgeMessages(ctx context.Context, policy RetentionPolicy, limit int) (int, error)
 }
 
 type Room struct {
@@ -20,12 +34,14 @@ type Room struct {
 	Name string `json:"roomName" db:"room_name"`
 	// inbound forwards Messages to members
 	inbound chan Message
-	// shutdown stops the Room
+	// shutdown is closed once the Room has stopped
 	shutdown chan struct{}
 	// ready is used to communicate the Start goroutine is running
 	ready chan struct{}
 	// sync.Once to close inbound
 	closeInbound *sync.Once
+	// sync.Once to close shutdown
+	closeShutdown *sync.Once
 	// callbackInbound is a hook for synchronizing goroutines in tests.
 	callbackInbound func(*Message)
 	// flag used in tests to turn off error logging
@@ -58,6 +74,7 @@ func NewRoom(name string) *Room {
 		shutdown:        make(chan struct{}),
 		ready:           make(chan struct{}),
 		closeInbound:    new(sync.Once),
+		closeShutdown:   new(sync.Once),
 		callbackInbound: func(*Message) {},
 		logErrors:       true,
 		mu:              new(sync.Mutex),
@@ -72,6 +89,7 @@ func SetupRoom(room *Room) {
 	room.shutdown = make(chan struct{})
 	room.ready = make(chan struct{})
 	room.closeInbound = new(sync.Once)
+	room.closeShutdown = new(sync.Once)
 	room.callbackInbound = func(*Message) {}
 	room.logErrors = true
 	room.mu = new(sync.Mutex)
@@ -84,32 +102,53 @@ func (r *Room) AddMember(member *Member) {
 	r.mu.Unlock()
 }
 
+// Members returns a copy of the local Members of the room
 func (r *Room) Members() map[string]*Member {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	return r.members
+	members := make(map[string]*Member, len(r.members))
+	for id, member := range r.members {
+		members[id] = member
+	}
+	return members
 }
 
 // Room constantly listens for messages and forwards them to existing members
 func (r *Room) Start(ctx context.Context) {
+	defer r.stop()
 	r.ready <- struct{}{}
 	for {
 		message, ok := <-r.inbound
 		if !ok {
-			for _, member := range r.members {
-				member.CloseInbound()
+			for _, member := range r.Members() {
 				member.StopBroadcast()
+				member.CloseInbound()
 			}
 			return
 		}
 		switch message.Type {
 		case MemberLeft:
+			r.mu.Lock()
 			delete(r.members, message.MemberID)
-			for _, member := range r.members {
+			r.mu.Unlock()
+			for _, member := range r.Members() {
+				r.notifyMember(ctx, message, *member)
+			}
+		case RoomExpired:
+			if message.RoomID != r.ID {
+				break
+			}
+			// stop reading from members first so closing their connections
+			// is not mistaken for the members leaving the room
+			for _, member := range r.Members() {
+				member.StopBroadcast()
 				r.notifyMember(ctx, message, *member)
+				member.CloseInbound()
 			}
+			r.callbackInbound(&message)
+			return
 		default:
-			for _, member := range r.members {
+			for _, member := range r.Members() {
 				r.notifyMember(ctx, message, *member)
 			}
 		}
@@ -117,6 +156,17 @@ func (r *Room) Start(ctx context.Context) {
 	}
 }
 
+// Done returns a channel that is closed once the Room has stopped.
+func (r *Room) Done() <-chan struct{} {
+	return r.shutdown
+}
+
+func (r *Room) stop() {
+	r.closeShutdown.Do(func() {
+		close(r.shutdown)
+	})
+}
+
 func (r *Room) Inbound(ctx context.Context, message Message) error {
 	select {
 	case r.inbound <- message:
@@ -0,0 +1,100 @@
+package sharechat
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Search highlights wrap each matched term in the highlight of a SearchResult
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// SearchResult is a chat message matching a search query
+type SearchResult struct {
+	Message
+	// Rank scores how well the message matches the query; higher ranks are better.
+	// Ranks are only comparable between results of the same repository & query.
+	Rank float64 `json:"rank" db:"rank"`
+	// Highlight is an excerpt of the message with matched terms wrapped in HighlightStart & HighlightStop
+	Highlight string `json:"highlight" db:"highlight"`
+}
+
+// SearchCursor points at the last result of a page of search results.
+// Results are ordered by rank, then by most recent, so the cursor carries all three.
+type SearchCursor struct {
+	ID   string
+	Sent time.Time
+	Rank float64
+}
+
+func (s *SearchCursor) IsEmpty() bool {
+	return s.ID == "" && s.Sent.IsZero()
+}
+
+func (s *SearchCursor) Encode() string {
+	if s.IsEmpty() {
+		return ""
+	}
+
+	concat := fmt.Sprintf("%s,%s,%s", s.ID, s.Sent.Format(time.RFC3339Nano), strconv.FormatFloat(s.Rank, 'g', -1, 64))
+	return base64.StdEncoding.EncodeToString([]byte(concat))
+}
+
+func (s *SearchCursor) DecodeFromString(raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(string(decoded), ",")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid cursor format")
+	}
+
+	s.ID = parts[0]
+	s.Sent, err = time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return err
+	}
+
+	s.Rank, err = strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type SearchMessageOptions struct {
+	Limit  int
+	RoomID string
+	// Query is the free text to search for; only chat messages are searched
+	Query string
+	After SearchCursor
+}
+
+func (s *SearchMessageOptions) Validate() error {
+	if s.Limit < 0 {
+		return errors.Join(ErrInvalidOptions, errors.New("limit must be greater than 0"))
+	}
+
+	if s.RoomID == "" {
+		return errors.Join(ErrInvalidOptions, errors.New("room must be specified"))
+	}
+
+	if strings.TrimSpace(s.Query) == "" {
+		return errors.Join(ErrInvalidOptions, errors.New("query must not be empty"))
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	insertMemberQuery     = "INSERT INTO members (member_id, name, room_id) VALUES (?, ?, ?)"
+	getMembersByRoomQuery = "SELECT member_id, name, room_id FROM members WHERE room_id=? AND is_deleted=false"
+	deleteMemberQuery     = "UPDATE members SET is_deleted=true, updated_at=CURRENT_TIMESTAMP WHERE member_id=?"
+)
+
+func NewMemberRepository(db *sql.DB) *MemberRepository {
+	return &MemberRepository{db: sqlx.NewDb(db, driver)}
+}
+
+type MemberRepository struct {
+	db *sqlx.DB
+}
+
+func (m *MemberRepository) InsertMember(ctx context.Context, member sharechat.Member) (*sharechat.Message, error) {
+	message := sharechat.NewMemberJoinedMessage(member)
+	message.Sent = time.Now().UTC()
+
+	err := withTransaction(ctx, m.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, insertMemberQuery, member.ID, member.Name, member.RoomID); err != nil {
+			return err
+		}
+		return insertMessage(ctx, tx, &message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (m *MemberRepository) GetMembersByRoom(ctx context.Context, roomID string) (*[]sharechat.Member, error) {
+	members := []sharechat.Member{}
+	if err := m.db.SelectContext(ctx, &members, getMembersByRoomQuery, roomID); err != nil {
+		return nil, err
+	}
+
+	return &members, nil
+}
+
+func (m *MemberRepository) DeleteMember(ctx context.Context, member sharechat.Member) (*sharechat.Message, error) {
+	message := sharechat.NewMemberLeftMessage(member)
+	message.Sent = time.Now().UTC()
+
+	err := withTransaction(ctx, m.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteMemberQuery, member.ID); err != nil {
+			return err
+		}
+		return insertMessage(ctx, tx, &message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
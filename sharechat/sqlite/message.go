@@ -0,0 +1,369 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	// Writers are serialized, so incrementing the room's counter in the inserting transaction
+	// keeps sequence numbers gap-free. Replies are only numbered & inserted if the parent
+	// is a top-level chat message in the same room.
+	nextSeqQuery = `
+	UPDATE rooms SET last_seq=last_seq+1
+	WHERE room_id=?1 AND (?2 = '' OR EXISTS (
+		SELECT 1 FROM messages p
+		WHERE p.message_id=?2 AND p.room_id=?1 AND p.type='chat' AND p.parent_id IS NULL
+	))
+	RETURNING last_seq
+	`
+	insertMessageQuery = `
+	INSERT INTO messages (message_id, type, message, sent, room_id, member_id, parent_id, seq, cluster)
+	VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`
+	getMessagesQuery = `
+	SELECT message_id, type, message, sent, edited_at, m.room_id, m.member_id, IFNULL(parent_id, '') AS parent_id, m.seq, name AS member_name
+	FROM messages m JOIN members b ON m.member_id = b.member_id
+	WHERE 1=1
+	`
+	// MAX() drops the column's declared type, so last_reply comes back as text
+	getThreadSummariesQuery = `
+	SELECT parent_id, COUNT(*) AS reply_count, MAX(sent) AS last_reply FROM messages
+	WHERE parent_id IN (?)
+	GROUP BY parent_id
+	`
+	// timeFormat is the layout the driver uses to write times with _time_format=sqlite
+	timeFormat          = "2006-01-02 15:04:05.999999999-07:00"
+	getChatMessageQuery = `
+	SELECT message_id, type, message, sent, edited_at, room_id, member_id, seq
+	FROM messages WHERE message_id=? AND member_id=? AND type='chat'
+	`
+	updateMessageQuery = "UPDATE messages SET message=?, edited_at=?, updated_at=CURRENT_TIMESTAMP WHERE message_id=?"
+	deleteMessageQuery = "DELETE FROM messages WHERE message_id=?"
+
+	chatMessageExistsQuery = "SELECT EXISTS (SELECT 1 FROM messages WHERE message_id=? AND room_id=? AND type='chat')"
+	addReactionQuery       = "INSERT INTO reactions (message_id, member_id, emoji) VALUES (?, ?, ?) ON CONFLICT DO NOTHING"
+	removeReactionQuery    = "DELETE FROM reactions WHERE message_id=? AND member_id=? AND emoji=?"
+	getReactionCountsQuery = `
+	SELECT message_id, emoji, COUNT(*) AS count FROM reactions
+	WHERE message_id IN (?)
+	GROUP BY message_id, emoji
+	`
+)
+
+func NewMessageRepository(db *sql.DB) *MessageRepository {
+	return &MessageRepository{db: sqlx.NewDb(db, driver)}
+}
+
+type MessageRepository struct {
+	db *sqlx.DB
+}
+
+func (m *MessageRepository) InsertMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
+	message.Sent = time.Now().UTC()
+	err := withTransaction(ctx, m.db, func(tx *sqlx.Tx) error {
+		return insertMessage(ctx, tx, &message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// insertMessage assigns message the room's next sequence number, inserts it & adds it to the outbox to be published
+func insertMessage(ctx context.Context, tx *sqlx.Tx, message *sharechat.Message) error {
+	if err := tx.GetContext(ctx, &message.Seq, nextSeqQuery, message.RoomID, message.ParentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sharechat.ErrMessageNotFound
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		insertMessageQuery,
+		message.ID,
+		message.Type,
+		message.Message,
+		message.Sent.UTC(),
+		message.RoomID,
+		message.MemberID,
+		message.ParentID,
+		message.Seq,
+		message.Cluster,
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, insertOutboxQuery, message.Sent.UTC(), message.ID)
+	return err
+}
+
+func (m *MessageRepository) UpdateMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
+	var result sharechat.Message
+
+	err := withTransaction(ctx, m.db, func(tx *sqlx.Tx) error {
+		if err := getChatMessage(ctx, tx, &result, message); err != nil {
+			return err
+		}
+
+		editedAt := time.Now().UTC()
+		result.Message = message.Message
+		result.EditedAt = &editedAt
+		_, err := tx.ExecContext(ctx, updateMessageQuery, result.Message, editedAt, result.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.MemberName = message.MemberName
+	return &result, nil
+}
+
+func (m *MessageRepository) DeleteMessage(ctx context.Context, message sharechat.Message) (*sharechat.Message, error) {
+	var result sharechat.Message
+
+	err := withTransaction(ctx, m.db, func(tx *sqlx.Tx) error {
+		if err := getChatMessage(ctx, tx, &result, message); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, deleteMessageQuery, result.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.MemberName = message.MemberName
+	return &result, nil
+}
+
+// getChatMessage loads the chat message targeted by message, provided it was sent by the same member
+func getChatMessage(ctx context.Context, tx *sqlx.Tx, dest *sharechat.Message, message sharechat.Message) error {
+	err := tx.GetContext(ctx, dest, getChatMessageQuery, message.TargetID, message.MemberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sharechat.ErrMessageNotFound
+	}
+	return err
+}
+
+func (m *MessageRepository) GetMessages(ctx context.Context, options sharechat.GetMessageOptions) ([]sharechat.Message, error) {
+	result := []sharechat.Message{}
+
+	query, args := m.buildGetMessagesQuery(options)
+	if err := m.db.SelectContext(ctx, &result, query, args...); err != nil {
+		return nil, err
+	}
+
+	if err := m.attachReactions(ctx, result); err != nil {
+		return nil, err
+	}
+
+	if err := m.attachThreadSummaries(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type threadSummary struct {
+	ParentID   string `db:"parent_id"`
+	ReplyCount int    `db:"reply_count"`
+	LastReply  string `db:"last_reply"`
+}
+
+// attachThreadSummaries populates the reply count & last reply timestamp for each message
+func (m *MessageRepository) attachThreadSummaries(ctx context.Context, messages []sharechat.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	query, args, err := sqlx.In(getThreadSummariesQuery, ids)
+	if err != nil {
+		return err
+	}
+
+	var summaries []threadSummary
+	if err := m.db.SelectContext(ctx, &summaries, query, args...); err != nil {
+		return err
+	}
+
+	byParent := make(map[string]threadSummary, len(summaries))
+	for _, summary := range summaries {
+		byParent[summary.ParentID] = summary
+	}
+
+	for i := range messages {
+		summary, ok := byParent[messages[i].ID]
+		if !ok {
+			continue
+		}
+
+		lastReply, err := time.Parse(timeFormat, summary.LastReply)
+		if err != nil {
+			return err
+		}
+		messages[i].ReplyCount = summary.ReplyCount
+		messages[i].LastReply = &lastReply
+	}
+
+	return nil
+}
+
+type reactionCount struct {
+	MessageID string `db:"message_id"`
+	Emoji     string `db:"emoji"`
+	Count     int    `db:"count"`
+}
+
+// attachReactions populates the aggregated reaction counts for each message
+func (m *MessageRepository) attachReactions(ctx context.Context, messages []sharechat.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	query, args, err := sqlx.In(getReactionCountsQuery, ids)
+	if err != nil {
+		return err
+	}
+
+	var counts []reactionCount
+	if err := m.db.SelectContext(ctx, &counts, query, args...); err != nil {
+		return err
+	}
+
+	byMessage := make(map[string]map[string]int)
+	for _, count := range counts {
+		if _, ok := byMessage[count.MessageID]; !ok {
+			byMessage[count.MessageID] = make(map[string]int)
+		}
+		byMessage[count.MessageID][count.Emoji] = count.Count
+	}
+
+	for i := range messages {
+		messages[i].Reactions = byMessage[messages[i].ID]
+	}
+
+	return nil
+}
+
+func (m *MessageRepository) AddReaction(ctx context.Context, message sharechat.Message) error {
+	return withTransaction(ctx, m.db, func(tx *sqlx.Tx) error {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, chatMessageExistsQuery, message.TargetID, message.RoomID); err != nil {
+			return err
+		}
+		if !exists {
+			return sharechat.ErrMessageNotFound
+		}
+
+		result, err := tx.ExecContext(ctx, addReactionQuery, message.TargetID, message.MemberID, message.Emoji)
+		if err != nil {
+			return err
+		}
+
+		added, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if added == 0 {
+			return sharechat.ErrDuplicateReaction
+		}
+		return nil
+	})
+}
+
+func (m *MessageRepository) RemoveReaction(ctx context.Context, message sharechat.Message) error {
+	result, err := m.db.ExecContext(ctx, removeReactionQuery, message.TargetID, message.MemberID, message.Emoji)
+	if err != nil {
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return sharechat.ErrReactionNotFound
+	}
+
+	return nil
+}
+
+// buildGetMessagesQuery mirrors the cursor semantics of the postgres repository:
+// results are keyed on (sent, message_id), ascending when paging forward with an
+// after cursor and descending otherwise.
+func (m *MessageRepository) buildGetMessagesQuery(options sharechat.GetMessageOptions) (string, []interface{}) {
+	builder := strings.Builder{}
+	builder.WriteString(getMessagesQuery)
+	args := []interface{}{}
+
+	// default to 100 records per query
+	if options.Limit == 0 {
+		options.Limit = 100
+	}
+
+	if options.RoomID != "" {
+		builder.WriteString(" AND m.room_id=?")
+		args = append(args, options.RoomID)
+	}
+
+	if options.ThreadID != "" {
+		builder.WriteString(" AND m.parent_id=?")
+		args = append(args, options.ThreadID)
+	} else if !options.IncludeReplies {
+		builder.WriteString(" AND m.parent_id IS NULL")
+	}
+
+	switch {
+	case options.HasSeq():
+		if options.AfterSeq > 0 {
+			builder.WriteString(" AND m.seq > ?")
+			args = append(args, options.AfterSeq)
+		}
+		if options.BeforeSeq > 0 {
+			builder.WriteString(" AND m.seq < ?")
+			args = append(args, options.BeforeSeq)
+		}
+
+		if options.AfterSeq > 0 {
+			builder.WriteString(" ORDER BY m.seq ASC")
+		} else {
+			builder.WriteString(" ORDER BY m.seq DESC")
+		}
+	case !options.After.IsEmpty():
+		builder.WriteString(" AND (sent, message_id) > (?, ?) ORDER BY sent ASC, message_id")
+		args = append(args, options.After.Sent.UTC(), options.After.ID)
+	case !options.Before.IsEmpty():
+		builder.WriteString(" AND (sent, message_id) < (?, ?) ORDER BY sent DESC, message_id DESC")
+		args = append(args, options.Before.Sent.UTC(), options.Before.ID)
+	default:
+		builder.WriteString(" ORDER BY sent DESC, message_id DESC")
+	}
+
+	if options.Limit > 0 {
+		builder.WriteString(" LIMIT ?")
+		args = append(args, options.Limit)
+	}
+
+	return builder.String(), args
+}
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE members (
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    member_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    room_id TEXT NOT NULL REFERENCES rooms(room_id)
+);
+
+CREATE INDEX members_room_id_idx ON members (room_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX members_room_id_idx;
+DROP TABLE members;
+-- +goose StatementEnd
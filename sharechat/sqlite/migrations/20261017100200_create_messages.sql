@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE messages (
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    message_id TEXT PRIMARY KEY,
+    type TEXT NOT NULL CHECK (type IN ('chat', 'joined', 'left')),
+    message TEXT NOT NULL,
+    -- sent is written by the repository as a fixed-format UTC string so that
+    -- lexical ordering matches chronological ordering for cursor pagination
+    sent TIMESTAMP NOT NULL,
+    room_id TEXT NOT NULL REFERENCES rooms(room_id),
+    member_id TEXT NOT NULL REFERENCES members(member_id)
+);
+
+CREATE INDEX messages_room_id_sent_idx ON messages (room_id, sent, message_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX messages_room_id_sent_idx;
+DROP TABLE messages;
+-- +goose StatementEnd
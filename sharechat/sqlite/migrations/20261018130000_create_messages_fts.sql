@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- messages_fts is an external content index over messages.message, kept in sync by triggers
+CREATE VIRTUAL TABLE messages_fts USING fts5(
+    message,
+    content='messages',
+    content_rowid='rowid',
+    tokenize='porter unicode61'
+);
+
+INSERT INTO messages_fts (messages_fts) VALUES ('rebuild');
+
+CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+    INSERT INTO messages_fts (rowid, message) VALUES (new.rowid, new.message);
+END;
+
+CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+    INSERT INTO messages_fts (messages_fts, rowid, message) VALUES ('delete', old.rowid, old.message);
+END;
+
+CREATE TRIGGER messages_fts_update AFTER UPDATE OF message ON messages BEGIN
+    INSERT INTO messages_fts (messages_fts, rowid, message) VALUES ('delete', old.rowid, old.message);
+    INSERT INTO messages_fts (rowid, message) VALUES (new.rowid, new.message);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER messages_fts_update;
+DROP TRIGGER messages_fts_delete;
+DROP TRIGGER messages_fts_insert;
+DROP TABLE messages_fts;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rooms ADD COLUMN last_seq INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN seq INTEGER;
+
+-- number existing messages in the order they were sent
+UPDATE messages SET seq = (
+    SELECT COUNT(*) FROM messages p
+    WHERE p.room_id = messages.room_id AND (p.sent, p.message_id) <= (messages.sent, messages.message_id)
+);
+
+UPDATE rooms SET last_seq = (SELECT IFNULL(MAX(seq), 0) FROM messages m WHERE m.room_id = rooms.room_id);
+
+CREATE UNIQUE INDEX messages_room_id_seq_idx ON messages (room_id, seq);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX messages_room_id_seq_idx;
+ALTER TABLE messages DROP COLUMN seq;
+ALTER TABLE rooms DROP COLUMN last_seq;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a zero limit falls back to the global retention policy
+ALTER TABLE rooms ADD COLUMN retention_max_age_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE rooms ADD COLUMN retention_max_messages INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE rooms DROP COLUMN retention_max_messages;
+ALTER TABLE rooms DROP COLUMN retention_max_age_ms;
+-- +goose StatementEnd
@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose NO TRANSACTION
+-- messages is rebuilt with an INTEGER PRIMARY KEY, which VACUUM never renumbers unlike the implicit
+-- rowid, so that messages_fts can be an external content index keyed on it. Foreign keys are disabled
+-- while the table is rebuilt so that dropping the old table does not cascade to reactions, replies &
+-- the outbox; they can only be disabled outside of a transaction.
+-- +goose StatementBegin
+PRAGMA foreign_keys = OFF;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+BEGIN;
+
+DROP TRIGGER messages_fts_update;
+DROP TRIGGER messages_fts_delete;
+DROP TRIGGER messages_fts_insert;
+DROP TABLE messages_fts;
+
+CREATE TABLE messages_rebuilt (
+    id INTEGER PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    message_id TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL CHECK (type IN ('chat', 'joined', 'left')),
+    message TEXT NOT NULL,
+    sent TIMESTAMP NOT NULL,
+    room_id TEXT NOT NULL REFERENCES rooms(room_id),
+    member_id TEXT NOT NULL REFERENCES members(member_id),
+    edited_at TIMESTAMP,
+    parent_id TEXT REFERENCES messages(message_id) ON DELETE CASCADE,
+    seq INTEGER
+);
+
+INSERT INTO messages_rebuilt (id, created_at, updated_at, message_id, type, message, sent, room_id, member_id, edited_at, parent_id, seq)
+SELECT rowid, created_at, updated_at, message_id, type, message, sent, room_id, member_id, edited_at, parent_id, seq FROM messages;
+
+DROP TABLE messages;
+ALTER TABLE messages_rebuilt RENAME TO messages;
+
+CREATE INDEX messages_room_id_sent_idx ON messages (room_id, sent, message_id);
+CREATE INDEX messages_parent_id_sent_idx ON messages (parent_id, sent);
+CREATE UNIQUE INDEX messages_room_id_seq_idx ON messages (room_id, seq);
+
+-- messages_fts is an external content index over messages.message, kept in sync by triggers
+CREATE VIRTUAL TABLE messages_fts USING fts5(
+    message,
+    content='messages',
+    content_rowid='id',
+    tokenize='porter unicode61'
+);
+
+INSERT INTO messages_fts (messages_fts) VALUES ('rebuild');
+
+CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+    INSERT INTO messages_fts (rowid, message) VALUES (new.id, new.message);
+END;
+
+CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+    INSERT INTO messages_fts (messages_fts, rowid, message) VALUES ('delete', old.id, old.message);
+END;
+
+CREATE TRIGGER messages_fts_update AFTER UPDATE OF message ON messages BEGIN
+    INSERT INTO messages_fts (messages_fts, rowid, message) VALUES ('delete', old.id, old.message);
+    INSERT INTO messages_fts (rowid, message) VALUES (new.id, new.message);
+END;
+
+COMMIT;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+PRAGMA foreign_keys = ON;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- messages keeps its INTEGER PRIMARY KEY, which aliases the rowid the previous index was keyed on
+SELECT 1;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the cluster a message relayed by a Bridge was sent in, empty for messages sent in this one
+ALTER TABLE messages ADD COLUMN cluster TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE messages DROP COLUMN cluster;
+-- +goose StatementEnd
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	insertOutboxQuery = "INSERT INTO outbox (created_at, message_id, next_attempt_at) VALUES (?1, ?2, ?1)"
+	getDueOutboxQuery = `
+	SELECT message_id FROM outbox WHERE next_attempt_at <= ?
+	ORDER BY created_at, message_id
+	LIMIT ?
+	`
+	leaseOutboxQuery = "UPDATE outbox SET next_attempt_at=? WHERE message_id IN (?)"
+	getOutboxQuery   = `
+	SELECT m.message_id, m.type, m.message, m.sent, m.edited_at, m.room_id, m.member_id,
+		IFNULL(m.parent_id, '') AS parent_id, m.seq, m.cluster, b.name AS member_name, o.attempts
+	FROM outbox o
+	JOIN messages m ON o.message_id = m.message_id
+	JOIN members b ON m.member_id = b.member_id
+	WHERE o.message_id IN (?)
+	ORDER BY o.created_at, o.message_id
+	`
+	deleteOutboxQuery     = "DELETE FROM outbox WHERE message_id=?"
+	rescheduleOutboxQuery = "UPDATE outbox SET attempts=attempts+1, next_attempt_at=? WHERE message_id=?"
+)
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: sqlx.NewDb(db, driver)}
+}
+
+// OutboxRepository relays messages that the message & member repositories
+// add to the outbox in the same transaction that inserts them.
+type OutboxRepository struct {
+	db *sqlx.DB
+}
+
+func (o *OutboxRepository) ClaimPendingMessages(ctx context.Context, due time.Time, leaseUntil time.Time, limit int) ([]sharechat.OutboxMessage, error) {
+	result := []sharechat.OutboxMessage{}
+
+	err := withTransaction(ctx, o.db, func(tx *sqlx.Tx) error {
+		var ids []string
+		if err := tx.SelectContext(ctx, &ids, getDueOutboxQuery, due.UTC(), limit); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		query, args, err := sqlx.In(leaseOutboxQuery, leaseUntil.UTC(), ids)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+
+		query, args, err = sqlx.In(getOutboxQuery, ids)
+		if err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &result, query, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (o *OutboxRepository) MarkPublished(ctx context.Context, messageID string) error {
+	_, err := o.db.ExecContext(ctx, deleteOutboxQuery, messageID)
+	return err
+}
+
+func (o *OutboxRepository) MarkFailed(ctx context.Context, messageID string, nextAttempt time.Time) error {
+	_, err := o.db.ExecContext(ctx, rescheduleOutboxQuery, nextAttempt.UTC(), messageID)
+	return err
+}
@@ -0,0 +1,167 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+const (
+	insertRoomQuery         = "INSERT INTO rooms (room_id, room_name, created_at) VALUES (?, ?, ?)"
+	getRoomQuery            = "SELECT room_id, room_name FROM rooms WHERE room_id=?"
+	deleteRoomMessagesQuery = "DELETE FROM messages WHERE room_id=?"
+	deleteRoomMembersQuery  = "DELETE FROM members WHERE room_id=?"
+	deleteRoomQuery         = "DELETE FROM rooms WHERE room_id=?"
+	getRoomRetentionQuery   = "SELECT retention_max_age_ms, retention_max_messages FROM rooms WHERE room_id=?"
+	setRoomRetentionQuery   = "UPDATE rooms SET retention_max_age_ms=?, retention_max_messages=? WHERE room_id=?"
+	// purgeMessagesQuery compares ages in julian days, which sqlite parses from the stored timestamps;
+	// replies are removed by the cascade on parent_id
+	purgeMessagesQuery = `
+	DELETE FROM messages WHERE message_id IN (
+		SELECT message_id FROM (
+			SELECT m.message_id, m.sent, m.seq, r.last_seq,
+				CASE WHEN r.retention_max_age_ms > 0 THEN r.retention_max_age_ms ELSE ? END AS max_age_ms,
+				CASE WHEN r.retention_max_messages > 0 THEN r.retention_max_messages ELSE ? END AS max_messages
+			FROM messages m
+			JOIN rooms r ON m.room_id = r.room_id
+		)
+		WHERE (max_age_ms > 0 AND (julianday(?) - julianday(sent)) * 86400000.0 > max_age_ms)
+		OR (max_messages > 0 AND seq <= last_seq - max_messages)
+		ORDER BY sent
+		LIMIT ?
+	)
+	`
+	getInactiveRoomsQuery = `
+	SELECT r.room_id FROM rooms r
+	WHERE r.created_at < ?
+	AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.room_id = r.room_id AND m.sent >= ?)
+	`
+)
+
+func NewRoomRepository(db *sql.DB) *RoomRepository {
+	return &RoomRepository{db: sqlx.NewDb(db, driver)}
+}
+
+type RoomRepository struct {
+	db *sqlx.DB
+}
+
+func (r *RoomRepository) InsertRoom(ctx context.Context, room *sharechat.Room) error {
+	_, err := r.db.ExecContext(ctx, insertRoomQuery, room.ID, room.Name, time.Now().UTC())
+	return err
+}
+
+func (r *RoomRepository) GetRoom(ctx context.Context, roomID string) (*sharechat.Room, error) {
+	var room sharechat.Room
+	if err := r.db.GetContext(ctx, &room, getRoomQuery, roomID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("room %s does not exist: %w, %w", roomID, sharechat.ErrRoomNotFound, err)
+		}
+		return nil, err
+	}
+
+	sharechat.SetupRoom(&room)
+
+	return &room, nil
+}
+
+// DeleteRoom deletes the room along with all of its members and messages.
+func (r *RoomRepository) DeleteRoom(ctx context.Context, roomID string) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		for _, query := range []string{deleteRoomMessagesQuery, deleteRoomMembersQuery} {
+			if _, err := tx.ExecContext(ctx, query, roomID); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, deleteRoomQuery, roomID)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if deleted == 0 {
+			return sharechat.ErrRoomNotFound
+		}
+		return nil
+	})
+}
+
+func (r *RoomRepository) GetInactiveRooms(ctx context.Context, cutoff time.Time) ([]string, error) {
+	roomIDs := []string{}
+	if err := r.db.SelectContext(ctx, &roomIDs, getInactiveRoomsQuery, cutoff.UTC(), cutoff.UTC()); err != nil {
+		return nil, err
+	}
+
+	return roomIDs, nil
+}
+
+// roomRetention is how a room's retention policy override is stored
+type roomRetention struct {
+	MaxAgeMS    int64 `db:"retention_max_age_ms"`
+	MaxMessages int   `db:"retention_max_messages"`
+}
+
+func (r *RoomRepository) GetRoomRetention(ctx context.Context, roomID string) (*sharechat.RetentionPolicy, error) {
+	var retention roomRetention
+	err := r.db.GetContext(ctx, &retention, getRoomRetentionQuery, roomID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, sharechat.ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &sharechat.RetentionPolicy{
+		MaxAge:      time.Duration(retention.MaxAgeMS) * time.Millisecond,
+		MaxMessages: retention.MaxMessages,
+	}, nil
+}
+
+func (r *RoomRepository) SetRoomRetention(ctx context.Context, roomID string, policy sharechat.RetentionPolicy) error {
+	result, err := r.db.ExecContext(ctx, setRoomRetentionQuery, policy.MaxAge.Milliseconds(), policy.MaxMessages, roomID)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return sharechat.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+func (r *RoomRepository) PurgeMessages(ctx context.Context, policy sharechat.RetentionPolicy, limit int) (int, error) {
+	result, err := r.db.ExecContext(
+		ctx,
+		purgeMessagesQuery,
+		policy.MaxAge.Milliseconds(),
+		policy.MaxMessages,
+		time.Now().UTC(),
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
+}
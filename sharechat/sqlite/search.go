@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+// searchMessagesQuery negates bm25, which scores better matches lower,
+// so that ranks sort the same way as the other repositories.
+var searchMessagesQuery = fmt.Sprintf(`
+	SELECT message_id, type, message, sent, edited_at, room_id, member_id, parent_id, seq, member_name, rank, highlight
+	FROM (
+		SELECT m.message_id, m.type, m.message, m.sent, m.edited_at, m.room_id, m.member_id,
+			IFNULL(m.parent_id, '') AS parent_id, m.seq, b.name AS member_name,
+			-bm25(messages_fts) AS rank,
+			snippet(messages_fts, 0, '%s', '%s', '…', 32) AS highlight
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		JOIN members b ON m.member_id = b.member_id
+		WHERE messages_fts MATCH ?1 AND m.room_id=?2 AND m.type='chat'
+	)
+	WHERE ?3 = '' OR (rank, sent, message_id) < (?4, ?5, ?3)
+	ORDER BY rank DESC, sent DESC, message_id DESC
+	LIMIT ?6
+	`,
+	sharechat.HighlightStart,
+	sharechat.HighlightStop,
+)
+
+func (m *MessageRepository) SearchMessages(ctx context.Context, options sharechat.SearchMessageOptions) ([]sharechat.SearchResult, error) {
+	result := []sharechat.SearchResult{}
+
+	match := matchExpression(options.Query)
+	if match == "" {
+		return result, nil
+	}
+
+	// default to 100 records per query
+	if options.Limit == 0 {
+		options.Limit = 100
+	}
+
+	if err := m.db.SelectContext(
+		ctx,
+		&result,
+		searchMessagesQuery,
+		match,
+		options.RoomID,
+		options.After.ID,
+		options.After.Rank,
+		options.After.Sent.UTC(),
+		options.Limit,
+	); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// matchExpression quotes every term of query so that user input is never parsed as
+// FTS5 query syntax; the terms are implicitly combined with AND.
+func matchExpression(query string) string {
+	terms := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for i, term := range terms {
+		terms[i] = `"` + term + `"`
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"sync"
+
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite" // SQLite Driver
+)
+
+const driver = "sqlite"
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// gooseMu serializes migrations, since goose keeps its dialect and base FS in package globals
+var gooseMu sync.Mutex
+
+// Open opens the SQLite database stored at path. Foreign keys are enforced and
+// times are written in a sortable format so that cursor pagination can compare
+// timestamps directly in SQL.
+func Open(path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf(
+		"file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite",
+		path,
+	)
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only supports a single writer; serializing connections avoids
+	// SQLITE_BUSY errors when transactions overlap.
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// Migrate runs the embedded migrations against db. goose's dialect is restored
+// afterwards so that callers migrating other databases with goose are unaffected.
+func Migrate(db *sql.DB) error {
+	gooseMu.Lock()
+	defer gooseMu.Unlock()
+
+	previous, err := dialectName(goose.GetDialect())
+	if err != nil {
+		return err
+	}
+
+	goose.SetBaseFS(migrations)
+	defer goose.SetBaseFS(nil)
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		return err
+	}
+	defer func() { _ = goose.SetDialect(previous) }()
+
+	return goose.Up(db, "migrations")
+}
+
+// dialectName returns the name goose.SetDialect accepts for a dialect
+func dialectName(dialect goose.SQLDialect) (string, error) {
+	switch dialect.(type) {
+	case *goose.PostgresDialect:
+		return "postgres", nil
+	case *goose.MySQLDialect:
+		return "mysql", nil
+	case *goose.Sqlite3Dialect:
+		return "sqlite3", nil
+	case *goose.SqlServerDialect:
+		return "mssql", nil
+	case *goose.RedshiftDialect:
+		return "redshift", nil
+	case *goose.TiDBDialect:
+		return "tidb", nil
+	case *goose.ClickHouseDialect:
+		return "clickhouse", nil
+	default:
+		return "", fmt.Errorf("unknown goose dialect %T", dialect)
+	}
+}
@@ -0,0 +1,520 @@
+//go:build int || all
+
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func GetDB(t *testing.T) *sql.DB {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "sharechat.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAll(t *testing.T) {
+	db := GetDB(t)
+	ctx := context.Background()
+
+	roomRepo := sqlite.NewRoomRepository(db)
+	room := sharechat.NewRoom("test")
+	err := roomRepo.InsertRoom(ctx, room)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insertedRoom, err := roomRepo.GetRoom(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, room.ID, insertedRoom.ID, "room should have same ID")
+	assert.Equal(t, room.Name, insertedRoom.Name, "room should have same name")
+
+	memberRepo := sqlite.NewMemberRepository(db)
+	member := sharechat.NewMember("test", room.ID, nil)
+
+	insertTime := time.Now()
+	joinedMessage, err := memberRepo.InsertMember(ctx, *member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, member.ID, joinedMessage.MemberID, "inserted message should have member ID")
+	assert.Equal(t, sharechat.MemberJoined, joinedMessage.Type, "inserted message should be member joined type")
+	assert.Equal(t, member.Name, joinedMessage.MemberName, "joined message should equal member name")
+	assert.True(t, insertTime.Before(joinedMessage.Sent), "joined message should have more recent timestamp")
+
+	members, err := memberRepo.GetMembersByRoom(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, *members, 1, "member should be in database")
+	assert.Equal(t, member.ID, (*members)[0].ID, "member should be in database")
+
+	messageRepo := sqlite.NewMessageRepository(db)
+
+	testMessages := []sharechat.Message{*joinedMessage}
+	for i := 0; i < 5; i++ {
+		message, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte(
+			fmt.Sprintf("This is test message %d", i)),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testMessages = append(testMessages, *message)
+	}
+
+	cursor := sharechat.MessageCursor{}
+	// test the before cursor
+	for i := len(testMessages) - 1; i >= 0; i-- {
+		result, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{
+			Limit:  1,
+			RoomID: room.ID,
+			Before: cursor,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		require.Equal(t, 1, len(result), "should only return one message")
+		assert.Equal(t, testMessages[i].ID, result[0].ID, "should return the correct message")
+		assert.Equal(t, member.Name, result[0].MemberName, "should have member name on message")
+		assert.True(t, testMessages[i].Sent.Equal(result[0].Sent), "should preserve the sent timestamp")
+		cursor = sharechat.MessageCursor{
+			ID:   result[0].ID,
+			Sent: result[0].Sent,
+		}
+	}
+
+	// test the after cursor
+	for i := 1; i < len(testMessages); i++ {
+		result, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{
+			Limit:  1,
+			RoomID: room.ID,
+			After:  cursor,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		require.Equal(t, 1, len(result), "should only return one message")
+		assert.Equal(t, testMessages[i].ID, result[0].ID, "should return the correct message")
+		cursor = sharechat.MessageCursor{
+			ID:   result[0].ID,
+			Sent: result[0].Sent,
+		}
+	}
+
+	for i, message := range testMessages {
+		assert.Equal(t, int64(i+1), message.Seq, "messages should be numbered in the order they were inserted")
+	}
+
+	seqRange, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, AfterSeq: 1, BeforeSeq: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, seqRange, 2, "should return the messages strictly between the sequence numbers")
+	assert.Equal(t, testMessages[1].ID, seqRange[0].ID, "should return messages in ascending order")
+	assert.Equal(t, testMessages[2].ID, seqRange[1].ID)
+
+	seqRange, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, BeforeSeq: 3, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, seqRange, 1)
+	assert.Equal(t, int64(2), seqRange[0].Seq, "should return messages in descending order")
+
+	reactable, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("react to me")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, reactable.ID, "👍"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, reactable.ID, "🎉"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, reactable.ID, "👍"))
+	assert.ErrorIs(t, err, sharechat.ErrDuplicateReaction, "members can only react once per emoji")
+
+	err = messageRepo.AddReaction(ctx, sharechat.NewReactionAddedMessage(*member, "missing", "👍"))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "cannot react to a missing message")
+
+	err = messageRepo.RemoveReaction(ctx, sharechat.NewReactionRemovedMessage(*member, reactable.ID, "🎉"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = messageRepo.RemoveReaction(ctx, sharechat.NewReactionRemovedMessage(*member, reactable.ID, "🎉"))
+	assert.ErrorIs(t, err, sharechat.ErrReactionNotFound, "reaction should already be removed")
+
+	reacted, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, reacted, 1)
+	assert.Equal(t, reactable.ID, reacted[0].ID)
+	assert.Equal(t, map[string]int{"👍": 1}, reacted[0].Reactions, "reactions should be aggregated")
+	testMessages = append(testMessages, *reactable)
+
+	other := sharechat.NewMember("other", room.ID, nil)
+	editable, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("helo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = messageRepo.UpdateMessage(ctx, sharechat.NewMessageEditedMessage(*other, editable.ID, []byte("hijacked")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only the author should be able to edit")
+
+	edited, err := messageRepo.UpdateMessage(ctx, sharechat.NewMessageEditedMessage(*member, editable.ID, []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "hello", edited.Message, "message content should be updated")
+	require.NotNil(t, edited.EditedAt, "message should be marked as edited")
+
+	_, err = messageRepo.UpdateMessage(ctx, sharechat.NewMessageEditedMessage(*member, joinedMessage.ID, []byte("hello")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only chat messages can be edited")
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*other, editable.ID))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "only the author should be able to delete")
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*member, editable.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*member, editable.ID))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "message should already be deleted")
+
+	parent, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte("start a thread")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []sharechat.Message
+	for i := 0; i < 2; i++ {
+		reply, err := messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, parent.ID, []byte(
+			fmt.Sprintf("This is reply %d", i)),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, *reply)
+	}
+
+	_, err = messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, replies[0].ID, []byte("nested")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "replies cannot be nested")
+
+	_, err = messageRepo.InsertMessage(ctx, sharechat.NewReplyMessage(*member, "missing", []byte("orphan")))
+	assert.ErrorIs(t, err, sharechat.ErrMessageNotFound, "cannot reply to a missing message")
+
+	topLevel, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, topLevel, 1)
+	assert.Equal(t, parent.ID, topLevel[0].ID, "replies should not be returned with top-level messages")
+	assert.Equal(t, 2, topLevel[0].ReplyCount, "parent should summarize its replies")
+	require.NotNil(t, topLevel[0].LastReply, "parent should have a last reply timestamp")
+	assert.True(t, replies[1].Sent.Equal(*topLevel[0].LastReply), "last reply should be the newest reply")
+
+	everything, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, IncludeReplies: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	included := []string{}
+	for _, message := range everything {
+		included = append(included, message.ID)
+	}
+	assert.Contains(t, included, parent.ID, "top-level messages should be returned when replies are included")
+	assert.Contains(t, included, replies[0].ID, "replies should be returned with top-level messages when included")
+	assert.Contains(t, included, replies[1].ID, "replies should be returned with top-level messages when included")
+
+	thread, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, ThreadID: parent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, thread, 2, "thread should contain its replies")
+	assert.Equal(t, replies[1].ID, thread[0].ID, "should return newest reply first")
+	assert.Equal(t, parent.ID, thread[0].ParentID, "replies should reference their parent")
+
+	_, err = messageRepo.DeleteMessage(ctx, sharechat.NewMessageDeletedMessage(*member, parent.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thread, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID, ThreadID: parent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, thread, "replies should be deleted with their parent")
+
+	for _, content := range []string{
+		"upgrading the kubernetes cluster tonight",
+		"kubernetes kubernetes kubernetes",
+		"who wants lunch?",
+	} {
+		message, err := messageRepo.InsertMessage(ctx, sharechat.NewChatMessage(*member, []byte(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testMessages = append(testMessages, *message)
+	}
+
+	searchCursor := sharechat.SearchCursor{}
+	var searchResults []sharechat.SearchResult
+	for {
+		page, err := messageRepo.SearchMessages(ctx, sharechat.SearchMessageOptions{
+			Limit:  1,
+			RoomID: room.ID,
+			Query:  "Kubernetes",
+			After:  searchCursor,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		require.Len(t, page, 1, "should only return one result")
+		searchResults = append(searchResults, page...)
+		searchCursor = sharechat.SearchCursor{ID: page[0].ID, Sent: page[0].Sent, Rank: page[0].Rank}
+	}
+	require.Len(t, searchResults, 2, "should return every matching message once")
+	assert.Equal(t, "kubernetes kubernetes kubernetes", searchResults[0].Message.Message, "best match should be first")
+	assert.GreaterOrEqual(t, searchResults[0].Rank, searchResults[1].Rank, "results should be ranked")
+	assert.Contains(t, searchResults[1].Highlight, sharechat.HighlightStart+"kubernetes"+sharechat.HighlightStop, "matches should be highlighted")
+	assert.Equal(t, member.Name, searchResults[1].MemberName, "should have member name on result")
+
+	// deleted messages leave gaps that VACUUM may renumber
+	_, err = db.ExecContext(ctx, "VACUUM")
+	require.NoError(t, err)
+	vacuumed, err := messageRepo.SearchMessages(ctx, sharechat.SearchMessageOptions{RoomID: room.ID, Query: "kubernetes"})
+	require.NoError(t, err)
+	require.Len(t, vacuumed, 2, "search should survive a VACUUM")
+	assert.Equal(t, searchResults[0].ID, vacuumed[0].ID)
+	assert.Equal(t, searchResults[1].ID, vacuumed[1].ID)
+
+	for _, query := range []string{`"unbalanced`, "NEAR(kubernetes", "'); DROP TABLE messages; --", "lunch*"} {
+		_, err := messageRepo.SearchMessages(ctx, sharechat.SearchMessageOptions{RoomID: room.ID, Query: query})
+		require.NoError(t, err, "query syntax should not break the search")
+	}
+
+	hostile := sharechat.NewChatMessage(*member, []byte("it's a '); DROP TABLE messages; --"))
+	_, err = messageRepo.InsertMessage(ctx, hostile)
+	require.NoError(t, err, "quotes should not break the query")
+
+	// messages relayed by a Bridge keep their cluster in the outbox, so they are not relayed back
+	bridged := sharechat.NewRoom("bridged")
+	require.NoError(t, roomRepo.InsertRoom(ctx, bridged))
+	replicated := sharechat.NewMember("replicated", bridged.ID, nil)
+	replicated.Cluster = "us"
+	replicatedJoined, err := memberRepo.InsertMember(ctx, *replicated)
+	require.NoError(t, err)
+	relayed := sharechat.NewChatMessage(*replicated, []byte("relayed"))
+	relayed.Cluster = "us"
+	_, err = messageRepo.InsertMessage(ctx, relayed)
+	require.NoError(t, err)
+
+	outboxRepo := sqlite.NewOutboxRepository(db)
+	now := time.Now()
+	pending, err := outboxRepo.ClaimPendingMessages(ctx, now, now.Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed := map[string]sharechat.OutboxMessage{}
+	for _, message := range pending {
+		claimed[message.ID] = message
+	}
+	require.Contains(t, claimed, joinedMessage.ID, "inserted members should add their joined message to the outbox")
+	assert.Equal(t, member.Name, claimed[joinedMessage.ID].MemberName, "outbox messages should have the member name")
+	require.Contains(t, claimed, testMessages[1].ID, "inserted messages should be added to the outbox")
+	assert.Empty(t, claimed[testMessages[1].ID].Cluster)
+	require.Contains(t, claimed, relayed.ID)
+	assert.Equal(t, "us", claimed[relayed.ID].Cluster, "outbox messages should have the cluster they were relayed from")
+	require.Contains(t, claimed, replicatedJoined.ID)
+	assert.Equal(t, "us", claimed[replicatedJoined.ID].Cluster, "replicated members should tag their joined message")
+	require.NoError(t, roomRepo.DeleteRoom(ctx, bridged.ID))
+
+	pending, err = outboxRepo.ClaimPendingMessages(ctx, now, now.Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, pending, claimed[joinedMessage.ID], "claimed messages should be leased")
+
+	err = outboxRepo.MarkFailed(ctx, joinedMessage.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = outboxRepo.ClaimPendingMessages(ctx, now, now.Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.Len(t, pending, 1, "failed messages should be retried")
+	assert.Equal(t, joinedMessage.ID, pending[0].ID)
+	assert.Equal(t, 1, pending[0].Attempts, "failed attempts should be counted")
+
+	for id := range claimed {
+		if err := outboxRepo.MarkPublished(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err = outboxRepo.ClaimPendingMessages(ctx, now.Add(time.Hour), now.Add(time.Hour), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range pending {
+		assert.NotContains(t, claimed, message.ID, "published messages should be removed from the outbox")
+	}
+
+	leftMessage, err := memberRepo.DeleteMember(ctx, *member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sharechat.MemberLeft, leftMessage.Type, "deleted message should be member left type")
+
+	members, err = memberRepo.GetMembersByRoom(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, *members, "member should no longer be in database")
+
+	messages, err := messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, messages, len(testMessages)+2, "should return all messages")
+	assert.Equal(t, leftMessage.ID, messages[0].ID, "should return newest message first")
+
+	inactive, err := roomRepo.GetInactiveRooms(ctx, leftMessage.Sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, inactive, room.ID, "room with recent messages should not be inactive")
+
+	inactive, err = roomRepo.GetInactiveRooms(ctx, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, inactive, room.ID, "room without recent messages should be inactive")
+
+	retention, err := roomRepo.GetRoomRetention(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, retention.IsEmpty(), "rooms should not override the global retention policy by default")
+
+	err = roomRepo.SetRoomRetention(ctx, room.ID, sharechat.RetentionPolicy{MaxMessages: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retention, err = roomRepo.GetRoomRetention(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sharechat.RetentionPolicy{MaxMessages: 3}, *retention, "should store the room's retention policy")
+
+	err = roomRepo.SetRoomRetention(ctx, "missing", sharechat.RetentionPolicy{MaxMessages: 3})
+	assert.ErrorIs(t, err, sharechat.ErrRoomNotFound, "setting the retention of a missing room should fail")
+
+	purged, err := roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, purged, "should purge at most a batch of messages")
+	for purged == 2 {
+		if purged, err = roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{}, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.LessOrEqual(t, len(messages), 3, "should only keep the most recent messages of the room")
+	assert.Equal(t, leftMessage.ID, messages[0].ID, "should keep the newest message")
+
+	purged, err = roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{MaxAge: time.Hour}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, purged, "should keep messages younger than the max age")
+
+	time.Sleep(2 * time.Millisecond)
+	purged, err = roomRepo.PurgeMessages(ctx, sharechat.RetentionPolicy{MaxAge: time.Millisecond}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(messages), purged, "should purge messages older than the max age")
+
+	err = roomRepo.DeleteRoom(ctx, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = roomRepo.DeleteRoom(ctx, room.ID)
+	assert.ErrorIs(t, err, sharechat.ErrRoomNotFound, "deleting a missing room should fail")
+
+	_, err = roomRepo.GetRoom(ctx, room.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "room should be deleted")
+
+	messages, err = messageRepo.GetMessages(ctx, sharechat.GetMessageOptions{RoomID: room.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, messages, "messages should be deleted with the room")
+}
+
+func TestMigrateRestoresDialect(t *testing.T) {
+	require.NoError(t, goose.SetDialect("mysql"))
+	t.Cleanup(func() { _ = goose.SetDialect("postgres") })
+
+	// migrations may run concurrently, e.g. for several databases opened at once
+	errs := make([]error, 4)
+	var wg sync.WaitGroup
+	for i := range errs {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), fmt.Sprintf("sharechat-%d.db", i)))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = sqlite.Migrate(db)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	assert.IsType(t, &goose.MySQLDialect{}, goose.GetDialect())
+}
@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jmoiron/sqlx"
+)
+
+// withTransaction runs fn inside of a transaction, committing if fn succeeds
+// and rolling back otherwise.
+func withTransaction(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			err = multierror.Append(err, rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
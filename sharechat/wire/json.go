@@ -0,0 +1,20 @@
+package wire
+
+import "encoding/json"
+
+// JSON encodes envelopes as JSON. Decoding ignores unknown fields.
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(envelope *Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (jsonCodec) Unmarshal(data []byte, envelope *Envelope) error {
+	return json.Unmarshal(data, envelope)
+}
@@ -0,0 +1,58 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack encodes envelopes as MessagePack maps keyed by the same field names as JSON,
+// a more compact encoding that is cheaper to decode. Times use the MessagePack timestamp
+// extension. Decoding skips unknown fields of any type.
+var MessagePack Codec = msgpackCodec{}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+func (msgpackCodec) Marshal(envelope *Envelope) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	// the json tags name fields & leave out the same empty fields as JSON
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+	if err := encoder.Encode(envelope); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, envelope *Envelope) error {
+	reader := bytes.NewReader(data)
+	decoder := msgpack.NewDecoder(reader)
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(envelope); err != nil {
+		return err
+	}
+
+	if _, err := decoder.PeekCode(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("msgpack: %d trailing bytes", reader.Len())
+	}
+
+	// timestamps are decoded in the local time zone, but are published in UTC
+	envelope.Published = envelope.Published.UTC()
+	message := &envelope.Message
+	message.Sent = message.Sent.UTC()
+	for _, t := range []*time.Time{message.EditedAt, message.LastReply} {
+		if t != nil {
+			*t = t.UTC()
+		}
+	}
+	return nil
+}
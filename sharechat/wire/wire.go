@@ -0,0 +1,108 @@
+// Package wire encodes the messages published to a Queue. Every message travels in a versioned
+// Envelope, encoded by the Codec the publishing Queue is configured with. Decoding detects the
+// codec from the payload itself, so nodes configured with different codecs, or running different
+// versions, can share a Queue during a rolling deploy.
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+)
+
+// Version is the version of the Envelope written by this package. Envelopes of newer versions
+// are still decoded; fields this version does not know about are ignored.
+const Version = 1
+
+// Envelope wraps a Message published to a Queue
+type Envelope struct {
+	Version     int    `json:"v"`
+	ContentType string `json:"contentType"`
+	// Origin identifies the node that published the message
+	Origin    string            `json:"origin,omitempty"`
+	Published time.Time         `json:"published"`
+	Message   sharechat.Message `json:"message"`
+}
+
+// Codec encodes envelopes in a single format
+type Codec interface {
+	ContentType() string
+	Marshal(envelope *Envelope) ([]byte, error)
+	Unmarshal(data []byte, envelope *Envelope) error
+}
+
+// Codecs are the supported codecs by name
+var Codecs = map[string]Codec{
+	"json":    JSON,
+	"msgpack": MessagePack,
+}
+
+// ParseCodec returns the codec with the given name
+func ParseCodec(name string) (Codec, error) {
+	codec, ok := Codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	return codec, nil
+}
+
+// NewEncoder creates an Encoder that encodes messages with codec, on behalf of the node origin
+func NewEncoder(codec Codec, origin string) *Encoder {
+	return &Encoder{codec: codec, origin: origin}
+}
+
+type Encoder struct {
+	codec  Codec
+	origin string
+}
+
+func (e *Encoder) Encode(message sharechat.Message) ([]byte, error) {
+	return e.codec.Marshal(&Envelope{
+		Version:     Version,
+		ContentType: e.codec.ContentType(),
+		Origin:      e.origin,
+		Published:   time.Now().UTC(),
+		Message:     message,
+	})
+}
+
+// Decode decodes an envelope encoded by any of the supported codecs. Payloads published before
+// messages were wrapped in envelopes, which are a bare JSON message, are decoded as an envelope
+// of version 0.
+func Decode(data []byte) (*Envelope, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("empty payload")
+	}
+
+	var envelope Envelope
+	if trimmed[0] != '{' {
+		if err := MessagePack.Unmarshal(data, &envelope); err != nil {
+			return nil, err
+		}
+		return &envelope, nil
+	}
+
+	// bare messages have no version, and their text is where the envelope holds the message
+	var version struct {
+		Version int `json:"v"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return nil, err
+	}
+	if version.Version == 0 {
+		envelope.ContentType = JSON.ContentType()
+		if err := json.Unmarshal(data, &envelope.Message); err != nil {
+			return nil, err
+		}
+		return &envelope, nil
+	}
+
+	if err := JSON.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	return &envelope, nil
+}
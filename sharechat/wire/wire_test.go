@@ -0,0 +1,173 @@
+//go:build unit || all
+
+package wire_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMessage() sharechat.Message {
+	member := sharechat.NewMember("test", "room", nil)
+	message := sharechat.NewChatMessage(*member, []byte(strings.Repeat("hello ", 50)))
+	message.Sent = time.Now().UTC()
+	editedAt := message.Sent.Add(time.Minute)
+	message.EditedAt = &editedAt
+	message.Seq = 1 << 40
+	message.Reactions = map[string]int{"👍": 3, "🎉": 300}
+	message.ParentID = "parent"
+	message.ReplyCount = 2
+	message.LastReply = &editedAt
+	message.Cluster = "eu"
+	return message
+}
+
+func TestCodecs(t *testing.T) {
+	message := testMessage()
+
+	sizes := map[string]int{}
+	for name, codec := range wire.Codecs {
+		payload, err := wire.NewEncoder(codec, "node-a").Encode(message)
+		require.NoError(t, err, name)
+		sizes[name] = len(payload)
+
+		envelope, err := wire.Decode(payload)
+		require.NoError(t, err, name)
+		assert.Equal(t, wire.Version, envelope.Version, name)
+		assert.Equal(t, codec.ContentType(), envelope.ContentType, name)
+		assert.Equal(t, "node-a", envelope.Origin, name)
+		assert.WithinDuration(t, time.Now(), envelope.Published, time.Minute, name)
+
+		decoded := envelope.Message
+		assert.True(t, message.Sent.Equal(decoded.Sent), name)
+		assert.True(t, message.EditedAt.Equal(*decoded.EditedAt), name)
+		assert.True(t, message.LastReply.Equal(*decoded.LastReply), name)
+		decoded.Sent, decoded.EditedAt, decoded.LastReply = message.Sent, message.EditedAt, message.LastReply
+		assert.Equal(t, message, decoded, name)
+	}
+
+	assert.Less(t, sizes["msgpack"], sizes["json"], "msgpack should be more compact than json")
+}
+
+// filledMessage sets every field of a Message, so that a field a codec leaves out fails the round trip
+func filledMessage(t *testing.T) sharechat.Message {
+	sent := time.Date(2026, time.October, 17, 12, 30, 45, 123456789, time.UTC)
+	var message sharechat.Message
+
+	v := reflect.ValueOf(&message).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field, name := v.Field(i), v.Type().Field(i).Name
+		switch value := field.Addr().Interface().(type) {
+		case *time.Time:
+			*value = sent
+		case **time.Time:
+			later := sent.Add(time.Duration(i) * time.Minute)
+			*value = &later
+		case *map[string]int:
+			*value = map[string]int{"👍": 3, name: 1 << 20}
+		default:
+			switch field.Kind() {
+			case reflect.String:
+				field.SetString(name)
+			case reflect.Int, reflect.Int64:
+				field.SetInt(int64(i) << 32)
+			default:
+				t.Fatalf("field %s of kind %s is not covered by the round trip test", name, field.Kind())
+			}
+		}
+	}
+	return message
+}
+
+func TestCodecsRoundTripEveryField(t *testing.T) {
+	message := filledMessage(t)
+
+	for name, codec := range wire.Codecs {
+		payload, err := wire.NewEncoder(codec, "node-a").Encode(message)
+		require.NoError(t, err, name)
+
+		envelope, err := wire.Decode(payload)
+		require.NoError(t, err, name)
+		assert.Equal(t, message, envelope.Message, "%s should round trip every field of a message", name)
+	}
+}
+
+func TestDecodeLegacyPayload(t *testing.T) {
+	message := testMessage()
+	payload, err := json.Marshal(message)
+	require.NoError(t, err)
+
+	envelope, err := wire.Decode(payload)
+	require.NoError(t, err)
+	assert.Equal(t, 0, envelope.Version, "bare messages predate envelopes")
+	assert.Equal(t, message.ID, envelope.Message.ID)
+	assert.Equal(t, message.Message, envelope.Message.Message)
+}
+
+func TestDecodeNewerVersions(t *testing.T) {
+	message := testMessage()
+
+	// JSON: unknown fields in the envelope & the message
+	payload, err := wire.NewEncoder(wire.JSON, "node-a").Encode(message)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &fields))
+	fields["v"] = wire.Version + 1
+	fields["trace"] = map[string]interface{}{"id": "abc", "sampled": true}
+	fields["message"].(map[string]interface{})["mentions"] = []string{"someone"}
+	payload, err = json.Marshal(fields)
+	require.NoError(t, err)
+
+	envelope, err := wire.Decode(payload)
+	require.NoError(t, err)
+	assert.Equal(t, wire.Version+1, envelope.Version)
+	assert.Equal(t, message.ID, envelope.Message.ID)
+
+	// MessagePack: append unknown fields of every type to the envelope map,
+	// whose header is a fixmap of 5 fields
+	payload, err = wire.NewEncoder(wire.MessagePack, "node-a").Encode(message)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x85), payload[0])
+
+	unknown := [][]byte{
+		{0xa1, 'a', 0xcb, 0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18},      // float64
+		{0xa1, 'b', 0x92, 0xc3, 0xa2, 'h', 'i'},                                // array of a bool & a string
+		{0xa1, 'c', 0x81, 0xa1, 'k', 0xd1, 0xff, 0x00},                         // map of an int16
+		{0xa1, 'd', 0xc4, 0x02, 0x01, 0x02},                                    // bin
+		{0xa1, 'e', 0xd5, 0x07, 0x01, 0x02},                                    // fixext 2
+		{0xa1, 'f', 0xc7, 0x03, 0x07, 0x01, 0x02, 0x03},                        // ext 8
+		{0xa1, 'g', 0xdc, 0x00, 0x02, 0xc0, 0xcd, 0x01, 0x00},                  // array 16 of nil & uint16
+		{0xa1, 'h', 0xd6, 0xff, 0x00, 0x00, 0x00, 0x01},                        // 32-bit timestamp
+		{0xa1, 'i', 0xde, 0x00, 0x01, 0xa1, 'x', 0xca, 0x3f, 0x80, 0x00, 0x00}, // map 16 of a float32
+	}
+	extended := append([]byte{0x80 | byte(5+len(unknown))}, payload[1:]...)
+	for _, field := range unknown {
+		extended = append(extended, field...)
+	}
+
+	envelope, err = wire.Decode(extended)
+	require.NoError(t, err)
+	assert.Equal(t, "node-a", envelope.Origin)
+	assert.Equal(t, message.ID, envelope.Message.ID)
+	assert.Equal(t, message.Reactions, envelope.Message.Reactions)
+
+	_, err = wire.Decode(extended[:len(extended)-1])
+	assert.Error(t, err, "truncated payloads should fail to decode")
+}
+
+func TestParseCodec(t *testing.T) {
+	codec, err := wire.ParseCodec("msgpack")
+	require.NoError(t, err)
+	assert.Equal(t, wire.MessagePack, codec)
+
+	_, err = wire.ParseCodec("xml")
+	assert.Error(t, err)
+}
@@ -136,8 +136,9 @@ func ServeRoom(t *testing.T, e *httpexpect.Expect, roomID string) {
 		r.Value("messages").Array().Length().Le(2)
 
 		for _, message := range r.Value("messages").Array().Iter() {
-			message.Object().Keys().ContainsOnly("id", "roomId", "memberId", "memberName", "type", "message", "sent")
+			message.Object().Keys().ContainsOnly("id", "roomId", "memberId", "memberName", "type", "message", "sent", "seq")
 			message.Object().Value("roomId").String().Equal(roomID)
+			message.Object().Value("seq").Number().Gt(0)
 			message.Object().Value("memberName").String().NotEmpty()
 			receivedMessages[message.Object().Value("id").String().Raw()] = struct{}{}
 		}
@@ -148,13 +149,53 @@ func ServeRoom(t *testing.T, e *httpexpect.Expect, roomID string) {
 
 }
 
+// DeleteRoom deletes a room joined on the public API e through the admin API admin
+func DeleteRoom(t *testing.T, e, admin *httpexpect.Expect) {
+	roomID := e.POST("/api/room").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("roomId").String().Raw()
+
+	ws := e.GET("/api/serve/{roomID}").WithPath("roomID", roomID).
+		WithWebsocketUpgrade().
+		Expect().
+		Status(http.StatusSwitchingProtocols).
+		Websocket()
+	defer ws.Disconnect()
+
+	ws.Expect().
+		JSON().
+		Object().
+		ValueEqual("type", sharechat.MemberJoined)
+
+	e.DELETE("/api/room/{roomID}").WithPath("roomID", roomID).
+		Expect().
+		Status(http.StatusMethodNotAllowed)
+	admin.DELETE("/api/room/{roomID}").WithPath("roomID", roomID).
+		Expect().
+		Status(http.StatusNoContent)
+
+	// connected members are told the room expired before being disconnected
+	ws.Expect().
+		JSON().
+		Object().
+		ValueEqual("type", sharechat.RoomExpired)
+
+	admin.DELETE("/api/room/{roomID}").WithPath("roomID", roomID).
+		Expect().
+		Status(http.StatusNotFound)
+}
+
 func TestMemory(t *testing.T) {
-	roomRepo := memory.NewRoomRepo()
 	messageRepo := memory.NewMessageRepo()
 	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
 	controller := sharechat.NewController(sharechat.NewControllerInput{
 		RoomRepo:    roomRepo,
 		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
 		MemberRepo:  memberRepo,
 		Queue:       memory.NewQueue(),
 	})
@@ -165,6 +206,92 @@ func TestMemory(t *testing.T) {
 	ServeNewRoom(t, e)
 }
 
+func TestMemoryAdminServer(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    memory.NewRoomRepo(memberRepo, messageRepo),
+		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
+		MemberRepo:  memberRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	server := sharechathttp.NewServer(controller, websocket.Upgrader{HandshakeTimeout: 5 * time.Second}, cors.Options{})
+	public := httptest.NewServer(server.Server.Handler)
+	defer public.Close()
+	httpexpect.New(t, public.URL).GET("/debug/vars").Expect().Status(http.StatusNotFound)
+
+	admin := httptest.NewServer(server.Admin.Handler)
+	defer admin.Close()
+	httpexpect.New(t, admin.URL).GET("/debug/vars").Expect().Status(http.StatusOK).
+		JSON().Object().ContainsKey("dedup_dropped_messages")
+}
+
+func TestMemoryDeleteRoom(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    roomRepo,
+		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
+		MemberRepo:  memberRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	server := sharechathttp.NewServer(controller, websocket.Upgrader{HandshakeTimeout: 5 * time.Second}, cors.Options{})
+	public := httptest.NewServer(server.Server.Handler)
+	defer public.Close()
+	admin := httptest.NewServer(server.Admin.Handler)
+	defer admin.Close()
+	DeleteRoom(t, httpexpect.New(t, public.URL), httpexpect.New(t, admin.URL))
+}
+
+func TestMemorySetRoomRetention(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    memory.NewRoomRepo(memberRepo, messageRepo),
+		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
+		MemberRepo:  memberRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	server := sharechathttp.NewServer(controller, websocket.Upgrader{HandshakeTimeout: 5 * time.Second}, cors.Options{})
+	public := httptest.NewServer(server.Server.Handler)
+	defer public.Close()
+	admin := httptest.NewServer(server.Admin.Handler)
+	defer admin.Close()
+	e := httpexpect.New(t, public.URL)
+
+	roomID := e.POST("/api/room").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("roomId").String().Raw()
+
+	retention := map[string]interface{}{"maxAge": "24h", "maxMessages": 10}
+	e.PUT("/api/room/{roomID}/retention").WithPath("roomID", roomID).
+		WithJSON(retention).
+		Expect().
+		Status(http.StatusMethodNotAllowed)
+	httpexpect.New(t, admin.URL).PUT("/api/room/{roomID}/retention").WithPath("roomID", roomID).
+		WithJSON(retention).
+		Expect().
+		Status(http.StatusNoContent)
+
+	e.GET("/api/room/{roomID}/retention").WithPath("roomID", roomID).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		ValueEqual("maxAge", "24h0m0s").
+		ValueEqual("maxMessages", 10)
+}
+
 func TestServeNewRoom(t *testing.T) {
 	dbstring := "user=user dbname=public password=password host=0.0.0.0 sslmode=disable"
 	driver := "postgres"
@@ -185,12 +312,14 @@ func TestServeNewRoom(t *testing.T) {
 		Password: "",
 	})
 
-	roomRepo := postgres.NewRoomRepository(db, "postgres")
-	messageRepo := postgres.NewMessageRepository(db, "postgres")
-	memberRepo := postgres.NewMemberRepository(db, "postgres")
+	pgdb := postgres.NewDB(db)
+	roomRepo := postgres.NewRoomRepository(pgdb)
+	messageRepo := postgres.NewMessageRepository(pgdb)
+	memberRepo := postgres.NewMemberRepository(pgdb)
 	controller := sharechat.NewController(sharechat.NewControllerInput{
 		RoomRepo:    roomRepo,
 		MessageRepo: messageRepo,
+		OutboxRepo:  postgres.NewOutboxRepository(pgdb),
 		MemberRepo:  memberRepo,
 		Queue:       redis.NewQueue(*redisClient),
 	})
@@ -221,12 +350,14 @@ func TestServeExistingRoom(t *testing.T) {
 		Password: "",
 	})
 
-	roomRepo := postgres.NewRoomRepository(db, "postgres")
-	messageRepo := postgres.NewMessageRepository(db, "postgres")
-	memberRepo := postgres.NewMemberRepository(db, "postgres")
+	pgdb := postgres.NewDB(db)
+	roomRepo := postgres.NewRoomRepository(pgdb)
+	messageRepo := postgres.NewMessageRepository(pgdb)
+	memberRepo := postgres.NewMemberRepository(pgdb)
 	controller := sharechat.NewController(sharechat.NewControllerInput{
 		RoomRepo:    roomRepo,
 		MessageRepo: messageRepo,
+		OutboxRepo:  postgres.NewOutboxRepository(pgdb),
 		MemberRepo:  memberRepo,
 		Queue:       redis.NewQueue(*redisClient),
 	})
@@ -0,0 +1,207 @@
+package e2e
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+	"github.com/gorilla/websocket"
+	"github.com/rs/cors"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	sharechathttp "github.com/soggycactus/sharechat.dev/sharechat/http"
+	"github.com/soggycactus/sharechat.dev/sharechat/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	Event string
+	ID    string
+	Data  string
+}
+
+// openEventStream streams the events of a room until the test ends or cancel is called
+func openEventStream(t *testing.T, url, roomID, lastEventID string) (<-chan event, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/api/room/"+roomID+"/events", nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	events := make(chan event)
+	go func() {
+		defer response.Body.Close()
+		defer close(events)
+
+		current := event{}
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				select {
+				case events <- current:
+				case <-ctx.Done():
+					return
+				}
+				current = event{}
+			case strings.HasPrefix(line, "event: "):
+				current.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "id: "):
+				current.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				current.Data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	return events, cancel
+}
+
+func nextEvent(t *testing.T, events <-chan event) event {
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "event stream ended")
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event was streamed")
+		return event{}
+	}
+}
+
+func nextMessage(t *testing.T, events <-chan event) (sharechat.Message, string) {
+	e := nextEvent(t, events)
+	var message sharechat.Message
+	require.NoError(t, json.Unmarshal([]byte(e.Data), &message))
+	return message, e.ID
+}
+
+func openSession(t *testing.T, events <-chan event) sharechathttp.Session {
+	e := nextEvent(t, events)
+	require.Equal(t, "session", e.Event, "the first event should identify the session")
+	var session sharechathttp.Session
+	require.NoError(t, json.Unmarshal([]byte(e.Data), &session))
+	require.NotEmpty(t, session.ID)
+	return session
+}
+
+func TestMemoryEventStream(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    roomRepo,
+		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
+		MemberRepo:  memberRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	server := httptest.NewServer(sharechathttp.NewServer(controller, websocket.Upgrader{HandshakeTimeout: 5 * time.Second}, cors.Options{}).Server.Handler)
+	// Close waits for the event streams, which are disconnected by the cleanups registered after it
+	t.Cleanup(server.Close)
+	e := httpexpect.New(t, server.URL)
+
+	roomID := e.POST("/api/room").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("roomId").String().Raw()
+
+	e.GET("/api/room/{roomID}/events").WithPath("roomID", "missing").
+		Expect().
+		Status(http.StatusNotFound)
+
+	events, disconnect := openEventStream(t, server.URL, roomID, "")
+	session := openSession(t, events)
+
+	joined, _ := nextMessage(t, events)
+	assert.Equal(t, sharechat.MemberJoined, joined.Type)
+	assert.Equal(t, session.MemberID, joined.MemberID)
+
+	e.POST("/api/room/{roomID}/messages").WithPath("roomID", roomID).
+		WithHeader(sharechathttp.SessionHeader, session.ID).
+		WithText("hello world!").
+		Expect().
+		Status(http.StatusAccepted)
+
+	hello, lastEventID := nextMessage(t, events)
+	assert.Equal(t, sharechat.Chat, hello.Type)
+	assert.Equal(t, "hello world!", hello.Message)
+	assert.Equal(t, session.MemberID, hello.MemberID, "messages sent to the session should be sent by its member")
+	require.NotEmpty(t, lastEventID)
+
+	// sessions are bound to their room
+	e.POST("/api/room/{roomID}/messages").WithPath("roomID", roomID).
+		WithQuery("session", "unknown").
+		WithText("hello?").
+		Expect().
+		Status(http.StatusNotFound)
+	otherRoomID := e.POST("/api/room").Expect().Status(http.StatusOK).JSON().Object().Value("roomId").String().Raw()
+	e.POST("/api/room/{roomID}/messages").WithPath("roomID", otherRoomID).
+		WithQuery("session", session.ID).
+		WithText("hello?").
+		Expect().
+		Status(http.StatusNotFound)
+
+	disconnect()
+
+	// another member chats while the first is disconnected
+	otherEvents, _ := openEventStream(t, server.URL, roomID, "")
+	other := openSession(t, otherEvents)
+	e.POST("/api/room/{roomID}/messages").WithPath("roomID", roomID).
+		WithQuery("session", other.ID).
+		WithText("are you there?").
+		Expect().
+		Status(http.StatusAccepted)
+	reply, err := sharechat.EncodeEnvelope(sharechat.ReplyOp, sharechat.Payload{ID: hello.ID, Message: "welcome!"})
+	require.NoError(t, err)
+	e.POST("/api/room/{roomID}/messages").WithPath("roomID", roomID).
+		WithQuery("session", other.ID).
+		WithBytes(reply).
+		Expect().
+		Status(http.StatusAccepted)
+
+	require.Eventually(t, func() bool {
+		members := e.GET("/api/room/{roomID}").WithPath("roomID", roomID).
+			Expect().Status(http.StatusOK).JSON().Object().Value("members").Array()
+		return len(members.Raw()) == 1
+	}, 5*time.Second, 50*time.Millisecond, "disconnecting should leave the room")
+
+	e.POST("/api/room/{roomID}/messages").WithPath("roomID", roomID).
+		WithHeader(sharechathttp.SessionHeader, session.ID).
+		WithText("hello?").
+		Expect().
+		Status(http.StatusNotFound)
+
+	// resuming replays what was missed, replies included, without repeating what was received
+	resumed, _ := openEventStream(t, server.URL, roomID, lastEventID)
+	openSession(t, resumed)
+	var missed []sharechat.Message
+	for len(missed) < 2 {
+		message, _ := nextMessage(t, resumed)
+		require.NotEqual(t, hello.ID, message.ID)
+		if message.Type == sharechat.Chat {
+			missed = append(missed, message)
+		}
+	}
+	assert.Equal(t, "are you there?", missed[0].Message)
+	assert.Equal(t, other.MemberID, missed[0].MemberID)
+	assert.Equal(t, "welcome!", missed[1].Message)
+	assert.Equal(t, hello.ID, missed[1].ParentID, "replies should be replayed")
+}
@@ -0,0 +1,146 @@
+package e2e
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	sharechatgrpc "github.com/soggycactus/sharechat.dev/sharechat/grpc"
+	"github.com/soggycactus/sharechat.dev/sharechat/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func joinChat(t *testing.T, ctx context.Context, client sharechatgrpc.SharechatClient, roomID string) sharechatgrpc.Sharechat_ChatClient {
+	stream, err := client.Chat(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&sharechatgrpc.ChatRequest{
+		Request: &sharechatgrpc.ChatRequest_Join{Join: &sharechatgrpc.Join{RoomId: roomID}},
+	}))
+	return stream
+}
+
+func receive(t *testing.T, stream sharechatgrpc.Sharechat_ChatClient, messageType sharechatgrpc.MessageType) *sharechatgrpc.Message {
+	message, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, messageType, message.Type)
+	return message
+}
+
+func TestMemoryGRPC(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    roomRepo,
+		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
+		MemberRepo:  memberRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	listener := bufconn.Listen(1 << 20)
+	server := sharechatgrpc.NewServer(controller).Server
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := sharechatgrpc.NewSharechatClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	room, err := client.CreateRoom(ctx, &sharechatgrpc.CreateRoomRequest{})
+	require.NoError(t, err)
+	require.NotEmpty(t, room.Id)
+	require.NotEmpty(t, room.Name)
+
+	_, err = client.GetRoom(ctx, &sharechatgrpc.GetRoomRequest{RoomId: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	unjoined, err := client.Chat(ctx)
+	require.NoError(t, err)
+	require.NoError(t, unjoined.Send(&sharechatgrpc.ChatRequest{Request: &sharechatgrpc.ChatRequest_Text{Text: "hello?"}}))
+	_, err = unjoined.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "the first request should join a room")
+
+	first := joinChat(t, ctx, client, room.Id)
+	firstID := receive(t, first, sharechatgrpc.MessageType_MESSAGE_TYPE_JOINED).MemberId
+
+	require.NoError(t, first.Send(&sharechatgrpc.ChatRequest{Request: &sharechatgrpc.ChatRequest_Text{Text: "hello world!"}}))
+	hello := receive(t, first, sharechatgrpc.MessageType_MESSAGE_TYPE_CHAT)
+	assert.Equal(t, "hello world!", hello.Message)
+	assert.Equal(t, firstID, hello.MemberId)
+	assert.NotNil(t, hello.Sent)
+	assert.NotZero(t, hello.Seq)
+
+	second := joinChat(t, ctx, client, room.Id)
+	receive(t, second, sharechatgrpc.MessageType_MESSAGE_TYPE_JOINED)
+	receive(t, first, sharechatgrpc.MessageType_MESSAGE_TYPE_JOINED)
+
+	require.NoError(t, second.Send(&sharechatgrpc.ChatRequest{
+		Request: &sharechatgrpc.ChatRequest_React{React: &sharechatgrpc.Reaction{Id: hello.Id, Emoji: "👍"}},
+	}))
+	for _, stream := range []sharechatgrpc.Sharechat_ChatClient{first, second} {
+		reacted := receive(t, stream, sharechatgrpc.MessageType_MESSAGE_TYPE_REACTED)
+		assert.Equal(t, hello.Id, reacted.TargetId)
+		assert.Equal(t, "👍", reacted.Emoji)
+	}
+
+	require.NoError(t, first.Send(&sharechatgrpc.ChatRequest{
+		Request: &sharechatgrpc.ChatRequest_Typing{Typing: &sharechatgrpc.Typing{}},
+	}))
+	for _, stream := range []sharechatgrpc.Sharechat_ChatClient{first, second} {
+		assert.Equal(t, firstID, receive(t, stream, sharechatgrpc.MessageType_MESSAGE_TYPE_TYPING).MemberId)
+	}
+
+	require.NoError(t, first.Send(&sharechatgrpc.ChatRequest{
+		Request: &sharechatgrpc.ChatRequest_Ack{Ack: &sharechatgrpc.Ack{}},
+	}))
+	assert.Equal(t, string(sharechat.InvalidPayload), receive(t, first, sharechatgrpc.MessageType_MESSAGE_TYPE_ERROR).Code)
+
+	// only the author can edit a message
+	require.NoError(t, second.Send(&sharechatgrpc.ChatRequest{
+		Request: &sharechatgrpc.ChatRequest_Edit{Edit: &sharechatgrpc.Edit{Id: hello.Id, Message: "goodbye"}},
+	}))
+	receive(t, second, sharechatgrpc.MessageType_MESSAGE_TYPE_FAILED)
+
+	members, err := client.GetRoom(ctx, &sharechatgrpc.GetRoomRequest{RoomId: room.Id})
+	require.NoError(t, err)
+	assert.Equal(t, room.Name, members.Room.Name)
+	assert.Len(t, members.Members, 2)
+
+	messages, err := client.GetMessages(ctx, &sharechatgrpc.GetMessagesRequest{RoomId: room.Id, AfterSeq: hello.Seq - 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, messages.Messages)
+	assert.Equal(t, hello.Id, messages.Messages[0].Id)
+	assert.Equal(t, map[string]int64{"👍": 1}, messages.Messages[0].Reactions)
+
+	_, err = client.GetMessages(ctx, &sharechatgrpc.GetMessagesRequest{RoomId: room.Id, After: "not a cursor"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// closing the stream leaves the room
+	require.NoError(t, first.CloseSend())
+	left := receive(t, second, sharechatgrpc.MessageType_MESSAGE_TYPE_LEFT)
+	assert.Equal(t, firstID, left.MemberId)
+	for {
+		_, err := first.Recv()
+		if err != nil {
+			assert.ErrorIs(t, err, io.EOF)
+			break
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package e2e
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	"github.com/soggycactus/sharechat.dev/sharechat/irc"
+	"github.com/soggycactus/sharechat.dev/sharechat/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ircClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialIRC(t *testing.T, addr, nick string) *ircClient {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	client := &ircClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	client.send("NICK " + nick)
+	client.send("USER " + nick + " 0 * :" + nick)
+	client.expect("001")
+	return client
+}
+
+func (c *ircClient) send(line string) {
+	_, err := fmt.Fprintf(c.conn, "%s\r\n", line)
+	require.NoError(c.t, err)
+}
+
+// expect reads lines until one has the given command, skipping the others
+func (c *ircClient) expect(command string) irc.Line {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for {
+		raw, err := c.reader.ReadString('\n')
+		require.NoError(c.t, err, "expected %s", command)
+		line, ok := irc.ParseLine(raw)
+		if ok && line.Command == command {
+			return line
+		}
+	}
+}
+
+func TestMemoryIRC(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    roomRepo,
+		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
+		MemberRepo:  memberRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() { _ = irc.NewServer(controller).Serve(listener) }()
+
+	room, err := controller.CreateRoom(context.Background())
+	require.NoError(t, err)
+	channel := "#" + room.ID
+
+	first := dialIRC(t, listener.Addr().String(), "alice")
+	first.send("PING :keepalive")
+	assert.Equal(t, []string{irc.ServerName, "keepalive"}, first.expect("PONG").Params)
+
+	first.send("PRIVMSG " + channel + " :hello?")
+	first.expect("404")
+	first.send("JOIN #missing")
+	first.expect("403")
+
+	first.send("JOIN " + channel)
+	joined := first.expect("JOIN")
+	assert.True(t, strings.HasPrefix(joined.Prefix, "alice!alice@"))
+	assert.Equal(t, []string{channel}, joined.Params)
+	assert.Equal(t, room.Name, first.expect("332").Params[2])
+	assert.Equal(t, "alice", first.expect("353").Params[3])
+	first.expect("366")
+
+	second := dialIRC(t, listener.Addr().String(), "bob")
+	second.send("JOIN " + channel)
+	second.expect("JOIN")
+	names := strings.Fields(second.expect("353").Params[3])
+	assert.Len(t, names, 2)
+	assert.Contains(t, names, "bob")
+
+	// the other members of the room are shown by their names
+	joined = first.expect("JOIN")
+	bob := strings.Split(joined.Prefix, "!")[0]
+	assert.NotEqual(t, "bob", bob)
+
+	second.send("PRIVMSG " + channel + " :hello world!")
+	message := first.expect("PRIVMSG")
+	assert.Equal(t, bob, strings.Split(message.Prefix, "!")[0])
+	assert.Equal(t, []string{channel, "hello world!"}, message.Params)
+
+	messages, err := controller.GetMessages(context.Background(), sharechat.GetMessageOptions{RoomID: room.ID})
+	require.NoError(t, err)
+	require.NotEmpty(t, messages)
+	assert.Equal(t, "hello world!", messages[0].Message)
+
+	second.send("PART " + channel + " :bye")
+	assert.Equal(t, []string{channel, "bye"}, second.expect("PART").Params)
+	parted := first.expect("PART")
+	assert.Equal(t, bob, strings.Split(parted.Prefix, "!")[0])
+
+	second.send("PRIVMSG " + channel + " :still here?")
+	second.expect("404")
+
+	// the room expiring kicks its members
+	require.NoError(t, controller.DeleteRoom(context.Background(), room.ID))
+	kicked := first.expect("KICK")
+	assert.Equal(t, []string{channel, "alice", "room expired"}, kicked.Params)
+
+	first.send("QUIT :bye")
+	first.expect("ERROR")
+}
@@ -0,0 +1,136 @@
+package e2e
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect"
+	"github.com/gorilla/websocket"
+	"github.com/rs/cors"
+	"github.com/soggycactus/sharechat.dev/sharechat"
+	sharechathttp "github.com/soggycactus/sharechat.dev/sharechat/http"
+	"github.com/soggycactus/sharechat.dev/sharechat/memory"
+)
+
+func joinSession(e *httpexpect.Expect, roomID string) string {
+	return e.POST("/api/room/{roomID}/sessions").WithPath("roomID", roomID).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		ContainsKey("memberId").
+		ContainsKey("memberName").
+		Value("session").String().NotEmpty().Raw()
+}
+
+func poll(e *httpexpect.Expect, roomID, session string, timeout int) *httpexpect.Array {
+	return e.GET("/api/room/{roomID}/poll").WithPath("roomID", roomID).
+		WithQuery("session", session).
+		WithQuery("timeout", timeout).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("messages").Array()
+}
+
+func TestMemoryLongPolling(t *testing.T) {
+	messageRepo := memory.NewMessageRepo()
+	memberRepo := memory.NewMemberRepo(messageRepo)
+	roomRepo := memory.NewRoomRepo(memberRepo, messageRepo)
+	controller := sharechat.NewController(sharechat.NewControllerInput{
+		RoomRepo:    roomRepo,
+		MessageRepo: messageRepo,
+		OutboxRepo:  messageRepo,
+		MemberRepo:  memberRepo,
+		Queue:       memory.NewQueue(),
+	})
+
+	sessionTTL := 500 * time.Millisecond
+	httpServer := sharechathttp.NewServer(controller, websocket.Upgrader{HandshakeTimeout: 5 * time.Second}, cors.Options{}).
+		WithPollSessionTTL(sessionTTL)
+	server := httptest.NewServer(httpServer.Server.Handler)
+	defer server.Close()
+	admin := httptest.NewServer(httpServer.Admin.Handler)
+	defer admin.Close()
+	e := httpexpect.New(t, server.URL)
+
+	roomID := e.POST("/api/room").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("roomId").String().Raw()
+
+	e.POST("/api/room/{roomID}/sessions").WithPath("roomID", "missing").
+		Expect().
+		Status(http.StatusNotFound)
+
+	first := joinSession(e, roomID)
+	poll(e, roomID, first, 1).Element(0).Object().ValueEqual("type", sharechat.MemberJoined)
+
+	// polls time out empty
+	start := time.Now()
+	poll(e, roomID, first, 0).Empty()
+	if time.Since(start) > time.Second {
+		t.Fatal("poll did not time out")
+	}
+
+	// polls wait for messages
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		e.POST("/api/room/{roomID}/messages").WithPath("roomID", roomID).
+			WithHeader(sharechathttp.SessionHeader, first).
+			WithText("hello world!").
+			Expect().
+			Status(http.StatusAccepted)
+	}()
+	poll(e, roomID, first, 5).Element(0).Object().
+		ValueEqual("type", sharechat.Chat).
+		ValueEqual("message", "hello world!")
+
+	e.GET("/api/room/{roomID}/poll").WithPath("roomID", roomID).
+		WithQuery("session", "unknown").
+		Expect().
+		Status(http.StatusNotFound)
+
+	second := joinSession(e, roomID)
+	poll(e, roomID, first, 1).Element(0).Object().ValueEqual("type", sharechat.MemberJoined)
+	poll(e, roomID, second, 1).Element(0).Object().ValueEqual("type", sharechat.MemberJoined)
+
+	// only the second session keeps polling, so the first expires & leaves the room
+	deadline := time.Now().Add(5 * time.Second)
+	left := false
+	for !left && time.Now().Before(deadline) {
+		for _, message := range poll(e, roomID, second, 1).Iter() {
+			if message.Object().Value("type").String().Raw() == string(sharechat.MemberLeft) {
+				left = true
+			}
+		}
+	}
+	if !left {
+		t.Fatal("the expired session did not leave the room")
+	}
+
+	e.GET("/api/room/{roomID}/poll").WithPath("roomID", roomID).
+		WithQuery("session", first).
+		Expect().
+		Status(http.StatusNotFound)
+	e.POST("/api/room/{roomID}/messages").WithPath("roomID", roomID).
+		WithQuery("session", first).
+		WithText("hello?").
+		Expect().
+		Status(http.StatusNotFound)
+
+	// closed sessions can still poll what they were sent before closing
+	httpexpect.New(t, admin.URL).DELETE("/api/room/{roomID}").WithPath("roomID", roomID).
+		Expect().
+		Status(http.StatusNoContent)
+	poll(e, roomID, second, 1).Last().Object().ValueEqual("type", sharechat.RoomExpired)
+	e.GET("/api/room/{roomID}/poll").WithPath("roomID", roomID).
+		WithQuery("session", second).
+		Expect().
+		Status(http.StatusNotFound)
+}